@@ -0,0 +1,18 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/webook/internal/job"
+	"github.com/webook/internal/service"
+)
+
+// App holds everything running in the webook process
+type App struct {
+	server           *gin.Engine
+	scheduledPublish *job.ScheduledPublishJob
+	ranking          *job.RankingJob
+	// userEvents dispatches the user events from all replicas to the local streams
+	userEvents service.UserEventService
+	// search builds the in-process indexes on start
+	search service.SearchService
+}
@@ -1,6 +1,9 @@
 package domain
 
 import (
+	"github.com/webook/pkg/markup"
+	"math"
+	"strings"
 	"time"
 )
 
@@ -8,14 +11,18 @@ type Article struct {
 	Id        int64
 	Title     string
 	Content   string
+	Format    ContentFormat
 	Author    Author
 	Status    ArticleStatus
+	PublishAt time.Time // when a scheduled article will be published
+	Category  string
+	Tags      []string
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
 func (a Article) Abstract() string {
-	str := []rune(a.Content)
+	str := []rune(a.PlainText())
 	// content abstract
 	if len(str) > 128 {
 		str = str[:128]
@@ -23,6 +30,59 @@ func (a Article) Abstract() string {
 	return string(str)
 }
 
+// HTML renders the content into sanitized HTML, which is safe to show to readers
+func (a Article) HTML() string {
+	switch a.Format {
+	case ContentFormatMarkdown:
+		return markup.MarkdownToHTML(a.Content)
+	case ContentFormatHTML:
+		return markup.Sanitize(a.Content)
+	default:
+		return markup.PlainToHTML(a.Content)
+	}
+}
+
+// PlainText strips the markup, code blocks and images of the content
+func (a Article) PlainText() string {
+	if a.Format == ContentFormatPlain {
+		return strings.Join(strings.Fields(a.Content), " ")
+	}
+	return markup.PlainText(a.HTML())
+}
+
+// WordCount counts every CJK character as a word
+func (a Article) WordCount() int {
+	words, cjk := markup.CountWords(a.PlainText())
+	return words + cjk
+}
+
+// ReadingTime assumes 200 words or 400 CJK characters per minute, rounded up to minutes
+func (a Article) ReadingTime() time.Duration {
+	words, cjk := markup.CountWords(a.PlainText())
+	if words+cjk == 0 {
+		return 0
+	}
+	minutes := math.Ceil(float64(words)/200 + float64(cjk)/400)
+	return time.Duration(minutes) * time.Minute
+}
+
+type ContentFormat uint8
+
+func (f ContentFormat) ToUint8() uint8 {
+	return uint8(f)
+}
+
+func (f ContentFormat) Valid() bool {
+	return f <= ContentFormatHTML
+}
+
+const (
+	// ContentFormatPlain is the default, articles written before formats were introduced are plain
+	ContentFormatPlain = iota
+	ContentFormatMarkdown
+	ContentFormatHTML
+)
+
 type ArticleStatus uint8
 
 func (s ArticleStatus) ToUint8() uint8 {
@@ -35,9 +95,27 @@ const (
 	ArticleStatusUnpublished
 	ArticleStatusPublished
 	ArticleStatusPrivate
+	// ArticleStatusScheduled the draft will be published at PublishAt
+	ArticleStatusScheduled
 )
 
+// Tag is a normalized tag with the number of published articles using it
+type Tag struct {
+	Name  string
+	Count int64
+}
+
 type Author struct {
 	Id   int64
 	Name string
 }
+
+// ArticleRevision is an immutable snapshot of the article, taken every time the article is saved
+type ArticleRevision struct {
+	ArticleId int64
+	// Revision starts from 1 and increases on every save
+	Revision  int64
+	Title     string
+	Content   string
+	CreatedAt time.Time
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// Collection is a named folder of the articles a user collects.
+// The articles collected without a collection are in the default one, whose Id is 0
+type Collection struct {
+	Id   int64
+	Uid  int64
+	Name string
+	// Public collections are visible on the profile of the owner
+	Public    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// Comment is a comment on a published article.
+// Top-level comments have RootId 0, replies point to the top-level comment of their thread by RootId,
+// and to the comment they reply to by ParentId
+type Comment struct {
+	Id          int64
+	ArticleId   int64
+	Commentator Commentator
+	Content     string
+	RootId      int64
+	ParentId    int64
+	// Replies is only filled for top-level comments, with the first few replies
+	Replies   []Comment
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type Commentator struct {
+	Id   int64
+	Name string
+}
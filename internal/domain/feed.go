@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+// FeedEvent is an article showing up in feeds, Ctime is when it is first published
+type FeedEvent struct {
+	ArticleId int64
+	AuthorId  int64
+	Ctime     time.Time
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// FollowRelation means Follower follows Followee
+type FollowRelation struct {
+	Follower  int64
+	Followee  int64
+	CreatedAt time.Time
+}
+
+// FollowStatistics is how many users follow a user, and how many users the user follows
+type FollowStatistics struct {
+	Followers int64
+	Followees int64
+}
@@ -0,0 +1,17 @@
+package domain
+
+// BizArticle is the business type of articles in interactive counters
+const BizArticle = "article"
+
+// Interactive is the engagement data of a resource, like an article
+type Interactive struct {
+	Biz           string
+	BizId         int64
+	ReadCnt       int64
+	UniqueReadCnt int64 // each reader is counted at most once within the dedup window
+	LikeCnt       int64
+	CollectCnt    int64
+	// whether the current user liked or collected it
+	Liked     bool
+	Collected bool
+}
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// TopicNotification is the event bus topic of NotificationEvent
+const TopicNotification = "notification"
+
+const (
+	NotificationKindLogin          = "login"
+	NotificationKindProfileUpdated = "profile_updated"
+	NotificationKindPhoneBound     = "phone_bound"
+	NotificationKindWechatLinked   = "wechat_linked"
+)
+
+// NotificationEvent is something happening to Uid that Uid should know
+type NotificationEvent struct {
+	Uid     int64
+	Kind    string
+	Content string
+	Time    time.Time
+}
+
+// Notification is one entry in the notification center,
+// repeated unread events of the same kind within a week are collapsed into one entry
+type Notification struct {
+	Id   int64
+	Uid  int64
+	Kind string
+	// Content is the content of the latest event
+	Content string
+	// Count is how many events are collapsed into it
+	Count     int64
+	Read      bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Summary is the title shown in the notification center
+func (n Notification) Summary() string {
+	switch n.Kind {
+	case NotificationKindLogin:
+		if n.Count > 1 {
+			return fmt.Sprintf("%d new logins this week", n.Count)
+		}
+		return "New login"
+	case NotificationKindProfileUpdated:
+		if n.Count > 1 {
+			return fmt.Sprintf("Profile updated %d times this week", n.Count)
+		}
+		return "Profile updated"
+	case NotificationKindPhoneBound:
+		return "Phone bound"
+	case NotificationKindWechatLinked:
+		return "Wechat linked"
+	default:
+		return n.Content
+	}
+}
@@ -0,0 +1,7 @@
+package domain
+
+// HotArticle is an article on the hot list, the higher Score the hotter
+type HotArticle struct {
+	Article Article
+	Score   float64
+}
@@ -0,0 +1,22 @@
+package domain
+
+// SearchResult is what a search finds, the most relevant first
+type SearchResult struct {
+	Articles []Article
+	Users    []User
+}
+
+const (
+	SearchDocArticle = "article"
+	SearchDocUser    = "user"
+)
+
+// SearchIndexChange tells the replicas to update their indexes,
+// they reload the document from DB unless it is Removed
+type SearchIndexChange struct {
+	Kind    string
+	Id      int64
+	Removed bool
+	// Origin is the replica making the change, which has applied it already
+	Origin string
+}
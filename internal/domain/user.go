@@ -3,9 +3,10 @@ package domain
 import "time"
 
 type User struct {
-	Id       int64
-	Email    string
-	Password string
+	Id            int64
+	Email         string
+	EmailVerified bool
+	Password      string
 
 	Nickname string
 	Birthday time.Time // YYYY-MM-DD
@@ -29,6 +30,18 @@ type User struct {
 //return u.Email
 //}
 
+// EmailUnverified tells whether the user signed up by email and hasn't verified it,
+// the users without email are not limited
+func (u User) EmailUnverified() bool {
+	return u.Email != "" && !u.EmailVerified
+}
+
+// HasLoginBesidesWechat tells whether the user can still log in after unlinking Wechat,
+// either by email and password or by SMS
+func (u User) HasLoginBesidesWechat() bool {
+	return (u.Email != "" && u.Password != "") || u.Phone != ""
+}
+
 // TodayIsBirthday
 func (u User) TodayIsBirthday() bool {
 	now := time.Now()
@@ -0,0 +1,14 @@
+package domain
+
+// TopicUserEvent is the event bus topic of UserEvent
+const TopicUserEvent = "user_event"
+
+// UserEvent is pushed to the open event streams of Uid in real time.
+// Id is assigned when it is published, it increases so that clients can resume after it
+type UserEvent struct {
+	Id   string
+	Uid  int64
+	Type string
+	// Data is sent as it is, usually JSON
+	Data string
+}
@@ -0,0 +1,70 @@
+package job
+
+import (
+	"context"
+	"github.com/webook/internal/service"
+	"github.com/webook/pkg/lock"
+	"github.com/webook/pkg/logger"
+	"time"
+)
+
+// RankingJob computes the hot article list periodically.
+// Every webook replica runs it, but only the one getting the Redis lock computes in each round
+type RankingJob struct {
+	svc    service.RankingService
+	locker lock.Locker
+	l      logger.LoggerV1
+
+	interval time.Duration
+	timeout  time.Duration
+}
+
+func NewRankingJob(svc service.RankingService, locker lock.Locker,
+	l logger.LoggerV1, interval time.Duration) *RankingJob {
+	return &RankingJob{
+		svc:      svc,
+		locker:   locker,
+		l:        l,
+		interval: interval,
+		timeout:  time.Minute,
+	}
+}
+
+// Start runs the job in background until ctx is cancelled
+func (j *RankingJob) Start(ctx context.Context) {
+	go func() {
+		// compute once on startup, so that the hot list is there soon
+		j.run(ctx)
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.run(ctx)
+			}
+		}
+	}()
+}
+
+func (j *RankingJob) run(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, j.timeout)
+	defer cancel()
+	// the lock is not released on purpose, it expires by the next round,
+	// so that the other replicas don't compute the same list again in this round
+	_, err := j.locker.TryLock(ctx, "article:ranking:job", j.interval)
+	if err == lock.ErrLockNotHold {
+		return
+	}
+	if err != nil {
+		j.l.Error("Failed to lock ranking job", logger.Error(err))
+		return
+	}
+	start := time.Now()
+	if err = j.svc.RankTopN(ctx); err != nil {
+		j.l.Error("Failed to rank hot articles", logger.Error(err))
+		return
+	}
+	j.l.Info("Hot articles ranked", logger.Int64("ms", time.Since(start).Milliseconds()))
+}
@@ -0,0 +1,93 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"github.com/webook/internal/service"
+	"github.com/webook/pkg/lock"
+	"github.com/webook/pkg/logger"
+	"time"
+)
+
+// ScheduledPublishJob publishes the scheduled articles when they are due.
+// Every webook replica runs it, the Redis lock makes sure only one replica publishes a given article
+type ScheduledPublishJob struct {
+	svc    service.ArticleService
+	locker lock.Locker
+	l      logger.LoggerV1
+
+	interval  time.Duration
+	batchSize int
+	// lockExpiration should be longer than publishing one article takes
+	lockExpiration time.Duration
+	timeout        time.Duration
+}
+
+func NewScheduledPublishJob(svc service.ArticleService, locker lock.Locker,
+	l logger.LoggerV1, interval time.Duration) *ScheduledPublishJob {
+	return &ScheduledPublishJob{
+		svc:            svc,
+		locker:         locker,
+		l:              l,
+		interval:       interval,
+		batchSize:      100,
+		lockExpiration: time.Minute,
+		timeout:        time.Second * 10,
+	}
+}
+
+// Start runs the job in background until ctx is cancelled
+func (j *ScheduledPublishJob) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.run(ctx)
+			}
+		}
+	}()
+}
+
+func (j *ScheduledPublishJob) run(ctx context.Context) {
+	listCtx, cancel := context.WithTimeout(ctx, j.timeout)
+	arts, err := j.svc.ListDueScheduled(listCtx, time.Now(), j.batchSize)
+	cancel()
+	if err != nil {
+		j.l.Error("Failed to list scheduled articles", logger.Error(err))
+		return
+	}
+	for _, art := range arts {
+		j.publish(ctx, art.Id)
+	}
+}
+
+func (j *ScheduledPublishJob) publish(ctx context.Context, id int64) {
+	ctx, cancel := context.WithTimeout(ctx, j.timeout)
+	defer cancel()
+	l, err := j.locker.TryLock(ctx, fmt.Sprintf("article:scheduled_publish:%d", id), j.lockExpiration)
+	if err == lock.ErrLockNotHold {
+		// another replica is publishing it
+		return
+	}
+	if err != nil {
+		j.l.Error("Failed to lock scheduled article", logger.Int64("aid", id), logger.Error(err))
+		return
+	}
+	defer func() {
+		if err := l.Unlock(ctx); err != nil {
+			j.l.Warn("Failed to unlock scheduled article", logger.Int64("aid", id), logger.Error(err))
+		}
+	}()
+	published, err := j.svc.PublishScheduled(ctx, id)
+	if err != nil {
+		j.l.Error("Failed to publish scheduled article", logger.Int64("aid", id), logger.Error(err))
+		return
+	}
+	if published {
+		j.l.Info("Scheduled article published", logger.Int64("aid", id))
+	}
+}
@@ -0,0 +1,412 @@
+package repository
+
+import (
+	"context"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository/cache"
+	"github.com/webook/internal/repository/dao"
+	"log"
+	"strings"
+	"time"
+)
+
+var (
+	ErrArticleNotFound     = dao.ErrArticleNotFound
+	ErrArticleNotScheduled = dao.ErrArticleNotScheduled
+)
+
+type ArticleRepository interface {
+	Create(ctx context.Context, art domain.Article) (int64, error)
+	Update(ctx context.Context, art domain.Article) error
+	GetById(ctx context.Context, id int64) (domain.Article, error)
+	Sync(ctx context.Context, art domain.Article) (int64, error)
+	SyncStatus(ctx context.Context, uid int64, id int64, status domain.ArticleStatus) error
+	GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error)
+	GetPubById(ctx context.Context, id int64) (domain.Article, error)
+	Schedule(ctx context.Context, uid int64, id int64, publishAt time.Time) error
+	CancelSchedule(ctx context.Context, uid int64, id int64) error
+	FindScheduled(ctx context.Context, before time.Time, limit int) ([]domain.Article, error)
+	// PublishScheduled publishes the draft as it is in DB if it is still scheduled and due,
+	// otherwise it returns ErrArticleNotScheduled
+	PublishScheduled(ctx context.Context, id int64) (domain.Article, error)
+	GetPubByTag(ctx context.Context, tag string, offset int, limit int) ([]domain.Article, error)
+	PopularTags(ctx context.Context, n int) ([]domain.Tag, error)
+	ListPub(ctx context.Context, since time.Time, offset int, limit int) ([]domain.Article, error)
+	// LatestPub lists the latestPubSize latest published articles of the author for the syndication feeds,
+	// uid 0 lists those of all authors
+	LatestPub(ctx context.Context, uid int64) ([]domain.Article, error)
+}
+
+const (
+	// firstPageSize is how many articles are kept in the first page cache,
+	// any first page request no larger than it is served from cache
+	firstPageSize = 100
+	// latestPubSize is how many articles the syndication feeds show
+	latestPubSize = 20
+)
+
+type CachedArticleRepository struct {
+	dao      dao.ArticleDAO
+	cache    cache.ArticleCache
+	tagCache cache.TagCache
+}
+
+func NewCachedArticleRepository(dao dao.ArticleDAO, c cache.ArticleCache, tc cache.TagCache) ArticleRepository {
+	return &CachedArticleRepository{
+		dao:      dao,
+		cache:    c,
+		tagCache: tc,
+	}
+}
+
+func (repo *CachedArticleRepository) Create(ctx context.Context, art domain.Article) (int64, error) {
+	id, err := repo.dao.Insert(ctx, repo.toEntity(art))
+	if err != nil {
+		return 0, err
+	}
+	repo.delFirstPage(ctx, art.Author.Id)
+	return id, nil
+}
+
+func (repo *CachedArticleRepository) Update(ctx context.Context, art domain.Article) error {
+	err := repo.dao.UpdateById(ctx, repo.toEntity(art))
+	if err != nil {
+		return err
+	}
+	repo.delFirstPage(ctx, art.Author.Id)
+	if err = repo.cache.Del(ctx, art.Id); err != nil {
+		log.Println(err)
+	}
+	return nil
+}
+
+func (repo *CachedArticleRepository) GetById(ctx context.Context, id int64) (domain.Article, error) {
+	res, err := repo.cache.Get(ctx, id)
+	if err == nil {
+		return res, nil
+	}
+	art, err := repo.dao.GetById(ctx, id)
+	if err != nil {
+		return domain.Article{}, err
+	}
+	res = repo.toDomain(art)
+	if err = repo.cache.Set(ctx, res); err != nil {
+		log.Println(err)
+	}
+	return res, nil
+}
+
+func (repo *CachedArticleRepository) Sync(ctx context.Context, art domain.Article) (int64, error) {
+	oldTags := repo.publishedTags(ctx, art.Id)
+	id, err := repo.dao.Sync(ctx, repo.toEntity(art))
+	if err != nil {
+		return 0, err
+	}
+	art.Id = id
+	repo.afterSync(ctx, art, oldTags)
+	return id, nil
+}
+
+func (repo *CachedArticleRepository) PublishScheduled(ctx context.Context, id int64) (domain.Article, error) {
+	oldTags := repo.publishedTags(ctx, id)
+	entity, err := repo.dao.PublishScheduled(ctx, id, domain.ArticleStatusScheduled,
+		domain.ArticleStatusPublished, time.Now().UnixMilli())
+	if err != nil {
+		return domain.Article{}, err
+	}
+	art := repo.toDomain(entity)
+	repo.afterSync(ctx, art, oldTags)
+	return art, nil
+}
+
+// afterSync updates the tag counts and invalidates the caches after art is published
+func (repo *CachedArticleRepository) afterSync(ctx context.Context, art domain.Article, oldTags []string) {
+	repo.incrPopularTags(ctx, repo.subtract(art.Tags, oldTags), 1)
+	repo.incrPopularTags(ctx, repo.subtract(oldTags, art.Tags), -1)
+	repo.delFirstPage(ctx, art.Author.Id)
+	repo.delLatestPub(ctx, art.Author.Id)
+	if err := repo.cache.Del(ctx, art.Id); err != nil {
+		log.Println(err)
+	}
+	// readers will load the new version from DB on the next read
+	if err := repo.cache.DelPub(ctx, art.Id); err != nil {
+		log.Println(err)
+	}
+}
+
+func (repo *CachedArticleRepository) SyncStatus(ctx context.Context, uid int64, id int64, status domain.ArticleStatus) error {
+	oldTags := repo.publishedTags(ctx, id)
+	err := repo.dao.SyncStatus(ctx, uid, id, status.ToUint8())
+	if err != nil {
+		return err
+	}
+	if status != domain.ArticleStatusPublished {
+		repo.incrPopularTags(ctx, oldTags, -1)
+	}
+	repo.delFirstPage(ctx, uid)
+	repo.delLatestPub(ctx, uid)
+	if err = repo.cache.Del(ctx, id); err != nil {
+		log.Println(err)
+	}
+	if err = repo.cache.DelPub(ctx, id); err != nil {
+		log.Println(err)
+	}
+	return nil
+}
+
+func (repo *CachedArticleRepository) GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error) {
+	isFirstPage := offset == 0 && limit <= firstPageSize
+	if isFirstPage {
+		res, err := repo.cache.GetFirstPage(ctx, uid)
+		if err == nil {
+			return repo.truncate(res, limit), nil
+		}
+	}
+	size := limit
+	if isFirstPage {
+		// load the whole first page, so that it can be cached
+		size = firstPageSize
+	}
+	arts, err := repo.dao.GetByAuthor(ctx, uid, offset, size)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Article, 0, len(arts))
+	for _, art := range arts {
+		res = append(res, repo.toDomain(art))
+	}
+	if !isFirstPage {
+		return res, nil
+	}
+	repo.preCache(ctx, res)
+	if err = repo.cache.SetFirstPage(ctx, uid, res); err != nil {
+		log.Println(err)
+	}
+	return repo.truncate(res, limit), nil
+}
+
+func (repo *CachedArticleRepository) truncate(arts []domain.Article, limit int) []domain.Article {
+	if len(arts) > limit {
+		return arts[:limit]
+	}
+	return arts
+}
+
+func (repo *CachedArticleRepository) GetPubById(ctx context.Context, id int64) (domain.Article, error) {
+	res, err := repo.cache.GetPub(ctx, id)
+	if err == nil {
+		return res, nil
+	}
+	art, err := repo.dao.GetPubById(ctx, id)
+	if err != nil {
+		return domain.Article{}, err
+	}
+	res = repo.toDomain(dao.Article(art))
+	if err = repo.cache.SetPub(ctx, res); err != nil {
+		log.Println(err)
+	}
+	return res, nil
+}
+
+func (repo *CachedArticleRepository) Schedule(ctx context.Context, uid int64, id int64, publishAt time.Time) error {
+	err := repo.dao.Schedule(ctx, uid, id, domain.ArticleStatusScheduled, publishAt.UnixMilli())
+	if err != nil {
+		return err
+	}
+	repo.delFirstPage(ctx, uid)
+	repo.delLatestPub(ctx, uid)
+	if err = repo.cache.Del(ctx, id); err != nil {
+		log.Println(err)
+	}
+	return nil
+}
+
+func (repo *CachedArticleRepository) CancelSchedule(ctx context.Context, uid int64, id int64) error {
+	err := repo.dao.CancelSchedule(ctx, uid, id, domain.ArticleStatusScheduled, domain.ArticleStatusUnpublished)
+	if err != nil {
+		return err
+	}
+	repo.delFirstPage(ctx, uid)
+	repo.delLatestPub(ctx, uid)
+	if err = repo.cache.Del(ctx, id); err != nil {
+		log.Println(err)
+	}
+	return nil
+}
+
+func (repo *CachedArticleRepository) FindScheduled(ctx context.Context, before time.Time, limit int) ([]domain.Article, error) {
+	arts, err := repo.dao.FindScheduled(ctx, domain.ArticleStatusScheduled, before.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Article, 0, len(arts))
+	for _, art := range arts {
+		res = append(res, repo.toDomain(art))
+	}
+	return res, nil
+}
+
+func (repo *CachedArticleRepository) GetPubByTag(ctx context.Context, tag string, offset int, limit int) ([]domain.Article, error) {
+	arts, err := repo.dao.GetPubByTag(ctx, tag, domain.ArticleStatusPublished, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Article, 0, len(arts))
+	for _, art := range arts {
+		res = append(res, repo.toDomain(dao.Article(art)))
+	}
+	return res, nil
+}
+
+func (repo *CachedArticleRepository) PopularTags(ctx context.Context, n int) ([]domain.Tag, error) {
+	return repo.tagCache.TopPopular(ctx, n)
+}
+
+func (repo *CachedArticleRepository) ListPub(ctx context.Context, since time.Time, offset int, limit int) ([]domain.Article, error) {
+	arts, err := repo.dao.ListPub(ctx, domain.ArticleStatusPublished, since.UnixMilli(), offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Article, 0, len(arts))
+	for _, art := range arts {
+		res = append(res, repo.toDomain(dao.Article(art)))
+	}
+	return res, nil
+}
+
+func (repo *CachedArticleRepository) LatestPub(ctx context.Context, uid int64) ([]domain.Article, error) {
+	res, err := repo.cache.GetLatestPub(ctx, uid)
+	if err == nil {
+		return res, nil
+	}
+	var arts []dao.PublishedArticle
+	if uid == 0 {
+		arts, err = repo.dao.ListLatestPub(ctx, domain.ArticleStatusPublished, latestPubSize)
+	} else {
+		arts, err = repo.dao.GetPubByAuthor(ctx, uid, domain.ArticleStatusPublished, latestPubSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+	res = make([]domain.Article, 0, len(arts))
+	for _, art := range arts {
+		res = append(res, repo.toDomain(dao.Article(art)))
+	}
+	if err = repo.cache.SetLatestPub(ctx, uid, res); err != nil {
+		log.Println(err)
+	}
+	return res, nil
+}
+
+// publishedTags returns the tags counted in the popular tags,
+// which are the tags of the article if it is published now
+func (repo *CachedArticleRepository) publishedTags(ctx context.Context, id int64) []string {
+	if id <= 0 {
+		return nil
+	}
+	art, err := repo.dao.GetPubById(ctx, id)
+	if err != nil || art.Status != domain.ArticleStatusPublished {
+		return nil
+	}
+	return repo.toTags(art.Tags)
+}
+
+func (repo *CachedArticleRepository) incrPopularTags(ctx context.Context, tags []string, delta int64) {
+	if err := repo.tagCache.IncrPopular(ctx, tags, delta); err != nil {
+		// popular tags are not accurate, but it is fine
+		log.Println(err)
+	}
+}
+
+// subtract returns the tags in a but not in b
+func (repo *CachedArticleRepository) subtract(a []string, b []string) []string {
+	res := make([]string, 0, len(a))
+	for _, tag := range a {
+		found := false
+		for _, other := range b {
+			if tag == other {
+				found = true
+				break
+			}
+		}
+		if !found {
+			res = append(res, tag)
+		}
+	}
+	return res
+}
+
+func (repo *CachedArticleRepository) delFirstPage(ctx context.Context, uid int64) {
+	if err := repo.cache.DelFirstPage(ctx, uid); err != nil {
+		// the first page will be stale until it expires
+		log.Println(err)
+	}
+}
+
+// delLatestPub invalidates the feeds of the author and the whole site
+func (repo *CachedArticleRepository) delLatestPub(ctx context.Context, uid int64) {
+	for _, id := range []int64{uid, 0} {
+		if err := repo.cache.DelLatestPub(ctx, id); err != nil {
+			// the feed will be stale until it expires
+			log.Println(err)
+		}
+	}
+}
+
+// preCache warms up the first article of the author's list,
+// since it is most likely to be opened right after listing
+func (repo *CachedArticleRepository) preCache(ctx context.Context, arts []domain.Article) {
+	// do not cache big articles
+	const contentSizeThreshold = 1024 * 1024
+	if len(arts) > 0 && len(arts[0].Content) < contentSizeThreshold {
+		if err := repo.cache.Set(ctx, arts[0]); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func (repo *CachedArticleRepository) toDomain(art dao.Article) domain.Article {
+	return domain.Article{
+		Id:      art.Id,
+		Title:   art.Title,
+		Content: art.Content,
+		Format:  domain.ContentFormat(art.Format),
+		Author: domain.Author{
+			Id: art.AuthorId,
+		},
+		Status:    domain.ArticleStatus(art.Status),
+		PublishAt: repo.toTime(art.PublishAt),
+		Category:  art.Category,
+		Tags:      repo.toTags(art.Tags),
+		CreatedAt: time.UnixMilli(art.CreatedAt),
+		UpdatedAt: time.UnixMilli(art.UpdatedAt),
+	}
+}
+
+// toTime keeps 0 as the zero time, so that IsZero works
+func (repo *CachedArticleRepository) toTime(millis int64) time.Time {
+	if millis == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(millis)
+}
+
+func (repo *CachedArticleRepository) toTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, ",")
+}
+
+func (repo *CachedArticleRepository) toEntity(art domain.Article) dao.Article {
+	return dao.Article{
+		Id:       art.Id,
+		Title:    art.Title,
+		Content:  art.Content,
+		Format:   art.Format.ToUint8(),
+		AuthorId: art.Author.Id,
+		Status:   art.Status.ToUint8(),
+		Category: art.Category,
+		Tags:     strings.Join(art.Tags, ","),
+	}
+}
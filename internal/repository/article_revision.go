@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository/dao"
+	"time"
+)
+
+var ErrRevisionNotFound = dao.ErrRevisionNotFound
+
+type ArticleRevisionRepository interface {
+	Create(ctx context.Context, rev domain.ArticleRevision) (int64, error)
+	GetByArticle(ctx context.Context, aid int64) ([]domain.ArticleRevision, error)
+	GetByRevision(ctx context.Context, aid int64, revision int64) (domain.ArticleRevision, error)
+}
+
+type articleRevisionRepository struct {
+	dao dao.ArticleRevisionDAO
+	// maxRevisions is how many revisions are retained per article, 0 means no limit
+	maxRevisions int
+}
+
+func NewArticleRevisionRepository(dao dao.ArticleRevisionDAO, maxRevisions int) ArticleRevisionRepository {
+	return &articleRevisionRepository{
+		dao:          dao,
+		maxRevisions: maxRevisions,
+	}
+}
+
+func (repo *articleRevisionRepository) Create(ctx context.Context, rev domain.ArticleRevision) (int64, error) {
+	return repo.dao.Insert(ctx, dao.ArticleRevision{
+		ArticleId: rev.ArticleId,
+		Title:     rev.Title,
+		Content:   rev.Content,
+	}, repo.maxRevisions)
+}
+
+func (repo *articleRevisionRepository) GetByArticle(ctx context.Context, aid int64) ([]domain.ArticleRevision, error) {
+	revs, err := repo.dao.GetByArticle(ctx, aid)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.ArticleRevision, 0, len(revs))
+	for _, rev := range revs {
+		res = append(res, repo.toDomain(rev))
+	}
+	return res, nil
+}
+
+func (repo *articleRevisionRepository) GetByRevision(ctx context.Context, aid int64, revision int64) (domain.ArticleRevision, error) {
+	rev, err := repo.dao.GetByRevision(ctx, aid, revision)
+	if err != nil {
+		return domain.ArticleRevision{}, err
+	}
+	return repo.toDomain(rev), nil
+}
+
+func (repo *articleRevisionRepository) toDomain(rev dao.ArticleRevision) domain.ArticleRevision {
+	return domain.ArticleRevision{
+		ArticleId: rev.ArticleId,
+		Revision:  rev.Revision,
+		Title:     rev.Title,
+		Content:   rev.Content,
+		CreatedAt: time.UnixMilli(rev.CreatedAt),
+	}
+}
@@ -2,8 +2,11 @@ package cache
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/redis/go-redis/v9"
 	"github.com/webook/internal/domain"
+	"time"
 )
 
 type ArticleCache interface {
@@ -12,41 +15,141 @@ type ArticleCache interface {
 	DelFirstPage(ctx context.Context, uid int64) error
 	Get(ctx context.Context, id int64) (domain.Article, error)
 	Set(ctx context.Context, art domain.Article) error
+	Del(ctx context.Context, id int64) error
 	GetPub(ctx context.Context, id int64) (domain.Article, error)
 	SetPub(ctx context.Context, res domain.Article) error
+	DelPub(ctx context.Context, id int64) error
+	// GetLatestPub gets the latest published articles of the author, uid 0 is for all authors
+	GetLatestPub(ctx context.Context, uid int64) ([]domain.Article, error)
+	SetLatestPub(ctx context.Context, uid int64, arts []domain.Article) error
+	DelLatestPub(ctx context.Context, uid int64) error
 }
 
 type ArticleRedisCache struct {
 	client redis.Cmdable
+	// first page of the author's list, invalidated on every change
+	firstPageExpiration time.Duration
+	// draft detail, only pre-warmed for a short while after listing
+	expiration time.Duration
+	// published article, read a lot and changed rarely
+	pubExpiration time.Duration
 }
 
 func NewArticleRedisCache(client redis.Cmdable) ArticleCache {
 	return &ArticleRedisCache{
-		client: client,
+		client:              client,
+		firstPageExpiration: time.Minute * 10,
+		expiration:          time.Minute,
+		pubExpiration:       time.Minute * 10,
 	}
 }
 
 func (a *ArticleRedisCache) GetFirstPage(ctx context.Context, uid int64) ([]domain.Article, error) {
+	data, err := a.client.Get(ctx, a.firstPageKey(uid)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var res []domain.Article
+	err = json.Unmarshal(data, &res)
+	return res, err
 }
 
 func (a *ArticleRedisCache) SetFirstPage(ctx context.Context, uid int64, res []domain.Article) error {
-
+	// the list only shows abstracts, no need to cache the whole content
+	arts := make([]domain.Article, 0, len(res))
+	for _, art := range res {
+		art.Content = art.Abstract()
+		// the abstract is plain text already
+		art.Format = domain.ContentFormatPlain
+		arts = append(arts, art)
+	}
+	data, err := json.Marshal(arts)
+	if err != nil {
+		return err
+	}
+	return a.client.Set(ctx, a.firstPageKey(uid), data, a.firstPageExpiration).Err()
 }
 
 func (a *ArticleRedisCache) DelFirstPage(ctx context.Context, uid int64) error {
-
+	return a.client.Del(ctx, a.firstPageKey(uid)).Err()
 }
 
 func (a *ArticleRedisCache) Get(ctx context.Context, id int64) (domain.Article, error) {
-
+	return a.get(ctx, a.key(id))
 }
 
 func (a *ArticleRedisCache) Set(ctx context.Context, art domain.Article) error {
+	return a.set(ctx, a.key(art.Id), art, a.expiration)
+}
 
+func (a *ArticleRedisCache) Del(ctx context.Context, id int64) error {
+	return a.client.Del(ctx, a.key(id)).Err()
 }
-func (a *ArticleRedisCache) GetPub(ctx context.Context, id int64) (domain.Article, error) {
 
+func (a *ArticleRedisCache) GetPub(ctx context.Context, id int64) (domain.Article, error) {
+	return a.get(ctx, a.pubKey(id))
 }
+
 func (a *ArticleRedisCache) SetPub(ctx context.Context, res domain.Article) error {
+	return a.set(ctx, a.pubKey(res.Id), res, a.pubExpiration)
+}
+
+func (a *ArticleRedisCache) DelPub(ctx context.Context, id int64) error {
+	return a.client.Del(ctx, a.pubKey(id)).Err()
+}
+
+func (a *ArticleRedisCache) GetLatestPub(ctx context.Context, uid int64) ([]domain.Article, error) {
+	data, err := a.client.Get(ctx, a.latestPubKey(uid)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var res []domain.Article
+	err = json.Unmarshal(data, &res)
+	return res, err
+}
+
+func (a *ArticleRedisCache) SetLatestPub(ctx context.Context, uid int64, arts []domain.Article) error {
+	data, err := json.Marshal(arts)
+	if err != nil {
+		return err
+	}
+	return a.client.Set(ctx, a.latestPubKey(uid), data, a.pubExpiration).Err()
+}
+
+func (a *ArticleRedisCache) DelLatestPub(ctx context.Context, uid int64) error {
+	return a.client.Del(ctx, a.latestPubKey(uid)).Err()
+}
+
+func (a *ArticleRedisCache) get(ctx context.Context, key string) (domain.Article, error) {
+	data, err := a.client.Get(ctx, key).Bytes()
+	if err != nil {
+		return domain.Article{}, err
+	}
+	var art domain.Article
+	err = json.Unmarshal(data, &art)
+	return art, err
+}
+
+func (a *ArticleRedisCache) set(ctx context.Context, key string, art domain.Article, expiration time.Duration) error {
+	data, err := json.Marshal(art)
+	if err != nil {
+		return err
+	}
+	return a.client.Set(ctx, key, data, expiration).Err()
+}
+
+func (a *ArticleRedisCache) firstPageKey(uid int64) string {
+	return fmt.Sprintf("article:first_page:%d", uid)
+}
+
+func (a *ArticleRedisCache) key(id int64) string {
+	return fmt.Sprintf("article:detail:%d", id)
+}
+
+func (a *ArticleRedisCache) pubKey(id int64) string {
+	return fmt.Sprintf("article:pub:detail:%d", id)
+}
 
+func (a *ArticleRedisCache) latestPubKey(uid int64) string {
+	return fmt.Sprintf("article:pub:latest:%d", uid)
 }
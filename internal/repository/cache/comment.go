@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+type CommentCache interface {
+	GetCount(ctx context.Context, aid int64) (int64, error)
+	SetCount(ctx context.Context, aid int64, cnt int64) error
+	DelCount(ctx context.Context, aid int64) error
+}
+
+type CommentRedisCache struct {
+	client     redis.Cmdable
+	expiration time.Duration
+}
+
+func NewCommentRedisCache(client redis.Cmdable) CommentCache {
+	return &CommentRedisCache{
+		client:     client,
+		expiration: time.Minute * 10,
+	}
+}
+
+func (c *CommentRedisCache) GetCount(ctx context.Context, aid int64) (int64, error) {
+	return c.client.Get(ctx, c.countKey(aid)).Int64()
+}
+
+func (c *CommentRedisCache) SetCount(ctx context.Context, aid int64, cnt int64) error {
+	return c.client.Set(ctx, c.countKey(aid), cnt, c.expiration).Err()
+}
+
+func (c *CommentRedisCache) DelCount(ctx context.Context, aid int64) error {
+	return c.client.Del(ctx, c.countKey(aid)).Err()
+}
+
+func (c *CommentRedisCache) countKey(aid int64) string {
+	return fmt.Sprintf("comment:cnt:%d", aid)
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/webook/internal/domain"
+	"strconv"
+	"time"
+)
+
+const (
+	fieldFollowers = "followers"
+	fieldFollowees = "followees"
+)
+
+type FollowCache interface {
+	GetStatistics(ctx context.Context, uid int64) (domain.FollowStatistics, error)
+	SetStatistics(ctx context.Context, uid int64, statistics domain.FollowStatistics) error
+	// DelStatistics is called on both sides whenever someone follows or unfollows
+	DelStatistics(ctx context.Context, uids ...int64) error
+}
+
+type FollowRedisCache struct {
+	client     redis.Cmdable
+	expiration time.Duration
+}
+
+func NewFollowRedisCache(client redis.Cmdable) FollowCache {
+	return &FollowRedisCache{
+		client:     client,
+		expiration: time.Minute * 15,
+	}
+}
+
+func (c *FollowRedisCache) GetStatistics(ctx context.Context, uid int64) (domain.FollowStatistics, error) {
+	res, err := c.client.HGetAll(ctx, c.statisticsKey(uid)).Result()
+	if err != nil {
+		return domain.FollowStatistics{}, err
+	}
+	if len(res) == 0 {
+		return domain.FollowStatistics{}, ErrKeyNotExist
+	}
+	// the fields are written by SetStatistics, they are always numbers
+	followers, _ := strconv.ParseInt(res[fieldFollowers], 10, 64)
+	followees, _ := strconv.ParseInt(res[fieldFollowees], 10, 64)
+	return domain.FollowStatistics{
+		Followers: followers,
+		Followees: followees,
+	}, nil
+}
+
+func (c *FollowRedisCache) SetStatistics(ctx context.Context, uid int64, statistics domain.FollowStatistics) error {
+	key := c.statisticsKey(uid)
+	pipe := c.client.TxPipeline()
+	pipe.HSet(ctx, key,
+		fieldFollowers, statistics.Followers,
+		fieldFollowees, statistics.Followees)
+	pipe.Expire(ctx, key, c.expiration)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *FollowRedisCache) DelStatistics(ctx context.Context, uids ...int64) error {
+	keys := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		keys = append(keys, c.statisticsKey(uid))
+	}
+	return c.client.Del(ctx, keys...).Err()
+}
+
+func (c *FollowRedisCache) statisticsKey(uid int64) string {
+	return fmt.Sprintf("follow:statistics:%d", uid)
+}
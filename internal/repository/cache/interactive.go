@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/webook/internal/domain"
+	"strconv"
+	"time"
+)
+
+var (
+	//go:embed lua/incr_cnt.lua
+	luaIncrCnt string
+)
+
+const (
+	fieldReadCnt       = "read_cnt"
+	fieldUniqueReadCnt = "unique_read_cnt"
+	fieldLikeCnt       = "like_cnt"
+	fieldCollectCnt    = "collect_cnt"
+)
+
+type InteractiveCache interface {
+	// IncrReadCntIfPresent increases the unique read count as well if unique is true
+	IncrReadCntIfPresent(ctx context.Context, biz string, bizId int64, unique bool) error
+	IncrLikeCntIfPresent(ctx context.Context, biz string, bizId int64) error
+	DecrLikeCntIfPresent(ctx context.Context, biz string, bizId int64) error
+	IncrCollectCntIfPresent(ctx context.Context, biz string, bizId int64) error
+	DecrCollectCntIfPresent(ctx context.Context, biz string, bizId int64) error
+	Get(ctx context.Context, biz string, bizId int64) (domain.Interactive, error)
+	Set(ctx context.Context, intr domain.Interactive) error
+}
+
+type RedisInteractiveCache struct {
+	client     redis.Cmdable
+	expiration time.Duration
+}
+
+func NewInteractiveRedisCache(client redis.Cmdable) InteractiveCache {
+	return &RedisInteractiveCache{
+		client:     client,
+		expiration: time.Minute * 15,
+	}
+}
+
+func (r *RedisInteractiveCache) IncrReadCntIfPresent(ctx context.Context, biz string, bizId int64, unique bool) error {
+	err := r.incr(ctx, biz, bizId, fieldReadCnt, 1)
+	if err != nil || !unique {
+		return err
+	}
+	return r.incr(ctx, biz, bizId, fieldUniqueReadCnt, 1)
+}
+
+func (r *RedisInteractiveCache) IncrLikeCntIfPresent(ctx context.Context, biz string, bizId int64) error {
+	return r.incr(ctx, biz, bizId, fieldLikeCnt, 1)
+}
+
+func (r *RedisInteractiveCache) DecrLikeCntIfPresent(ctx context.Context, biz string, bizId int64) error {
+	return r.incr(ctx, biz, bizId, fieldLikeCnt, -1)
+}
+
+func (r *RedisInteractiveCache) IncrCollectCntIfPresent(ctx context.Context, biz string, bizId int64) error {
+	return r.incr(ctx, biz, bizId, fieldCollectCnt, 1)
+}
+
+func (r *RedisInteractiveCache) DecrCollectCntIfPresent(ctx context.Context, biz string, bizId int64) error {
+	return r.incr(ctx, biz, bizId, fieldCollectCnt, -1)
+}
+
+func (r *RedisInteractiveCache) Get(ctx context.Context, biz string, bizId int64) (domain.Interactive, error) {
+	res, err := r.client.HGetAll(ctx, r.key(biz, bizId)).Result()
+	if err != nil {
+		return domain.Interactive{}, err
+	}
+	if len(res) == 0 {
+		return domain.Interactive{}, ErrKeyNotExist
+	}
+	// the fields are written by Set and incr_cnt.lua, they are always numbers
+	readCnt, _ := strconv.ParseInt(res[fieldReadCnt], 10, 64)
+	uniqueReadCnt, _ := strconv.ParseInt(res[fieldUniqueReadCnt], 10, 64)
+	likeCnt, _ := strconv.ParseInt(res[fieldLikeCnt], 10, 64)
+	collectCnt, _ := strconv.ParseInt(res[fieldCollectCnt], 10, 64)
+	return domain.Interactive{
+		Biz:           biz,
+		BizId:         bizId,
+		ReadCnt:       readCnt,
+		UniqueReadCnt: uniqueReadCnt,
+		LikeCnt:       likeCnt,
+		CollectCnt:    collectCnt,
+	}, nil
+}
+
+func (r *RedisInteractiveCache) Set(ctx context.Context, intr domain.Interactive) error {
+	key := r.key(intr.Biz, intr.BizId)
+	err := r.client.HSet(ctx, key,
+		fieldReadCnt, intr.ReadCnt,
+		fieldUniqueReadCnt, intr.UniqueReadCnt,
+		fieldLikeCnt, intr.LikeCnt,
+		fieldCollectCnt, intr.CollectCnt).Err()
+	if err != nil {
+		return err
+	}
+	return r.client.Expire(ctx, key, r.expiration).Err()
+}
+
+func (r *RedisInteractiveCache) incr(ctx context.Context, biz string, bizId int64, field string, delta int64) error {
+	return r.client.Eval(ctx, luaIncrCnt, []string{r.key(biz, bizId)}, field, delta).Err()
+}
+
+func (r *RedisInteractiveCache) key(biz string, bizId int64) string {
+	return fmt.Sprintf("interactive:%s:%d", biz, bizId)
+}
@@ -0,0 +1,14 @@
+-- interactive:biz:biz_id
+local key = KEYS[1]
+-- read_cnt, unique_read_cnt, like_cnt or collect_cnt
+local cntKey = ARGV[1]
+local delta = tonumber(ARGV[2])
+local exists = redis.call("exists", key)
+-- only update the counter when it is cached
+-- otherwise it will be loaded from DB with the right value next time
+if exists == 1 then
+    redis.call("hincrby", key, cntKey, delta)
+    return 1
+else
+    return 0
+end
@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/webook/internal/domain"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrLocalCacheExpired = errors.New("Local cache expired or not set")
+
+type RankingCache interface {
+	// Replace replaces the whole hot list
+	Replace(ctx context.Context, arts []domain.HotArticle) error
+	// Get returns the whole hot list, the hottest first
+	Get(ctx context.Context) ([]domain.HotArticle, error)
+}
+
+// RankingRedisCache keeps the ranking in a sorted set of article ids,
+// and the abstracts of them in a hash, so that readers don't need to load them one by one
+type RankingRedisCache struct {
+	client redis.Cmdable
+	// should be much longer than the interval of the ranking job,
+	// so that the list survives a few failed runs
+	expiration time.Duration
+}
+
+func NewRankingRedisCache(client redis.Cmdable) RankingCache {
+	return &RankingRedisCache{
+		client:     client,
+		expiration: time.Hour,
+	}
+}
+
+func (r *RankingRedisCache) Replace(ctx context.Context, arts []domain.HotArticle) error {
+	members := make([]redis.Z, 0, len(arts))
+	items := make(map[string]any, len(arts))
+	for _, hot := range arts {
+		art := hot.Article
+		// the list only shows abstracts, the same as the first page of the author
+		art.Content = art.Abstract()
+		art.Format = domain.ContentFormatPlain
+		data, err := json.Marshal(art)
+		if err != nil {
+			return err
+		}
+		id := strconv.FormatInt(art.Id, 10)
+		members = append(members, redis.Z{Score: hot.Score, Member: id})
+		items[id] = data
+	}
+	// readers either see the old list or the new one
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, r.key(), r.itemsKey())
+	if len(members) > 0 {
+		pipe.ZAdd(ctx, r.key(), members...)
+		pipe.HSet(ctx, r.itemsKey(), items)
+		pipe.Expire(ctx, r.key(), r.expiration)
+		pipe.Expire(ctx, r.itemsKey(), r.expiration)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *RankingRedisCache) Get(ctx context.Context) ([]domain.HotArticle, error) {
+	zs, err := r.client.ZRevRangeWithScores(ctx, r.key(), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(zs) == 0 {
+		return nil, ErrKeyNotExist
+	}
+	ids := make([]string, 0, len(zs))
+	for _, z := range zs {
+		ids = append(ids, z.Member.(string))
+	}
+	items, err := r.client.HMGet(ctx, r.itemsKey(), ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.HotArticle, 0, len(zs))
+	for i, item := range items {
+		data, ok := item.(string)
+		if !ok {
+			// the hash expired or was replaced in between
+			continue
+		}
+		var art domain.Article
+		if err = json.Unmarshal([]byte(data), &art); err != nil {
+			return nil, err
+		}
+		res = append(res, domain.HotArticle{Article: art, Score: zs[i].Score})
+	}
+	return res, nil
+}
+
+func (r *RankingRedisCache) key() string {
+	return "article:ranking:hot"
+}
+
+func (r *RankingRedisCache) itemsKey() string {
+	return "article:ranking:hot:items"
+}
+
+// RankingLocalCache keeps the hot list in memory,
+// it is read on every request and refreshed from Redis once it expires
+type RankingLocalCache struct {
+	mu         sync.RWMutex
+	arts       []domain.HotArticle
+	ddl        time.Time
+	expiration time.Duration
+}
+
+func NewRankingLocalCache() *RankingLocalCache {
+	return &RankingLocalCache{
+		expiration: time.Minute,
+	}
+}
+
+func (c *RankingLocalCache) Set(arts []domain.HotArticle) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.arts = arts
+	c.ddl = time.Now().Add(c.expiration)
+}
+
+func (c *RankingLocalCache) Get() ([]domain.HotArticle, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.arts) == 0 || time.Now().After(c.ddl) {
+		return nil, ErrLocalCacheExpired
+	}
+	return c.arts, nil
+}
+
+// ForceGet ignores the expiration, it is the last resort when Redis is down
+func (c *RankingLocalCache) ForceGet() ([]domain.HotArticle, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.arts) == 0 {
+		return nil, ErrLocalCacheExpired
+	}
+	return c.arts, nil
+}
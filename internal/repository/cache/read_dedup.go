@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+type ReadDedupCache interface {
+	// Add records that reader read the resource,
+	// returns true if it is the first read of the reader in the current window
+	Add(ctx context.Context, biz string, bizId int64, reader string) (bool, error)
+}
+
+// ReadDedupRedisCache keeps a HyperLogLog of the readers per resource per window.
+// The windows are fixed, so a reader reading across the boundary counts twice,
+// and PFADD may take a new reader as seen by a small chance, both are fine for view counts
+type ReadDedupRedisCache struct {
+	client redis.Cmdable
+	window time.Duration
+}
+
+func NewReadDedupRedisCache(client redis.Cmdable, window time.Duration) ReadDedupCache {
+	return &ReadDedupRedisCache{
+		client: client,
+		window: window,
+	}
+}
+
+func (r *ReadDedupRedisCache) Add(ctx context.Context, biz string, bizId int64, reader string) (bool, error) {
+	key := r.key(biz, bizId, time.Now())
+	pipe := r.client.TxPipeline()
+	added := pipe.PFAdd(ctx, key, reader)
+	pipe.Expire(ctx, key, r.window)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	return added.Val() == 1, nil
+}
+
+func (r *ReadDedupRedisCache) key(biz string, bizId int64, now time.Time) string {
+	return fmt.Sprintf("interactive:read_dedup:%s:%d:%d", biz, bizId, now.UnixMilli()/r.window.Milliseconds())
+}
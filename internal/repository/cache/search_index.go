@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/redis/go-redis/v9"
+	"github.com/webook/internal/domain"
+	"log"
+)
+
+type SearchIndexCache interface {
+	// Publish broadcasts the change to all replicas
+	Publish(ctx context.Context, change domain.SearchIndexChange) error
+	// Subscribe receives the changes broadcast by all replicas, the channel is closed when ctx is done
+	Subscribe(ctx context.Context) <-chan domain.SearchIndexChange
+}
+
+// SearchIndexRedisCache broadcasts the changes by pub/sub, the replicas down at the moment miss them
+// and catch up when they rebuild the indexes on start
+type SearchIndexRedisCache struct {
+	client  redis.UniversalClient
+	channel string
+}
+
+func NewSearchIndexRedisCache(client redis.UniversalClient) SearchIndexCache {
+	return &SearchIndexRedisCache{
+		client:  client,
+		channel: "search_index",
+	}
+}
+
+func (c *SearchIndexRedisCache) Publish(ctx context.Context, change domain.SearchIndexChange) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	return c.client.Publish(ctx, c.channel, data).Err()
+}
+
+func (c *SearchIndexRedisCache) Subscribe(ctx context.Context) <-chan domain.SearchIndexChange {
+	pubsub := c.client.Subscribe(ctx, c.channel)
+	res := make(chan domain.SearchIndexChange, 1024)
+	go func() {
+		defer close(res)
+		defer pubsub.Close()
+		msgs := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				var change domain.SearchIndexChange
+				if err := json.Unmarshal([]byte(msg.Payload), &change); err != nil {
+					log.Println(err)
+					continue
+				}
+				res <- change
+			}
+		}
+	}()
+	return res
+}
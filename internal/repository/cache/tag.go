@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/webook/internal/domain"
+)
+
+type TagCache interface {
+	// IncrPopular adds delta to the counters of tags
+	IncrPopular(ctx context.Context, tags []string, delta int64) error
+	// TopPopular returns the n tags used by most published articles
+	TopPopular(ctx context.Context, n int) ([]domain.Tag, error)
+}
+
+type RedisTagCache struct {
+	client redis.Cmdable
+	// one sorted set, member is the tag, score is the number of published articles with it
+	key string
+}
+
+func NewTagCache(client redis.Cmdable) TagCache {
+	return &RedisTagCache{
+		client: client,
+		key:    "article:tags:popular",
+	}
+}
+
+func (t *RedisTagCache) IncrPopular(ctx context.Context, tags []string, delta int64) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	pipe := t.client.TxPipeline()
+	for _, tag := range tags {
+		pipe.ZIncrBy(ctx, t.key, float64(delta), tag)
+	}
+	// tags no longer used by any published article
+	pipe.ZRemRangeByScore(ctx, t.key, "-inf", "0")
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (t *RedisTagCache) TopPopular(ctx context.Context, n int) ([]domain.Tag, error) {
+	res, err := t.client.ZRevRangeWithScores(ctx, t.key, 0, int64(n-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]domain.Tag, 0, len(res))
+	for _, z := range res {
+		name, _ := z.Member.(string)
+		tags = append(tags, domain.Tag{
+			Name:  name,
+			Count: int64(z.Score),
+		})
+	}
+	return tags, nil
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/webook/internal/domain"
+	"log"
+	"time"
+)
+
+type UserEventCache interface {
+	// Append keeps evt in the recent events of evt.Uid and broadcasts it to all replicas,
+	// it returns evt with the id assigned
+	Append(ctx context.Context, evt domain.UserEvent) (domain.UserEvent, error)
+	// After returns the recent events of uid after id, the oldest first
+	After(ctx context.Context, uid int64, id string) ([]domain.UserEvent, error)
+	// Subscribe receives the events broadcast by all replicas, the channel is closed when ctx is done
+	Subscribe(ctx context.Context) <-chan domain.UserEvent
+}
+
+// UserEventRedisCache keeps the recent events of each user in a Redis stream for resumption,
+// whose ids are the event ids, and broadcasts them by pub/sub
+type UserEventRedisCache struct {
+	client redis.UniversalClient
+	// maxLen is how many recent events are kept per user
+	maxLen     int64
+	expiration time.Duration
+	channel    string
+}
+
+func NewUserEventRedisCache(client redis.UniversalClient) UserEventCache {
+	return &UserEventRedisCache{
+		client:     client,
+		maxLen:     100,
+		expiration: time.Hour,
+		channel:    "user_events",
+	}
+}
+
+func (c *UserEventRedisCache) Append(ctx context.Context, evt domain.UserEvent) (domain.UserEvent, error) {
+	key := c.key(evt.Uid)
+	id, err := c.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: c.maxLen,
+		Approx: true,
+		Values: map[string]any{
+			"type": evt.Type,
+			"data": evt.Data,
+		},
+	}).Result()
+	if err != nil {
+		return evt, err
+	}
+	evt.Id = id
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return evt, err
+	}
+	pipe := c.client.Pipeline()
+	pipe.Expire(ctx, key, c.expiration)
+	pipe.Publish(ctx, c.channel, data)
+	_, err = pipe.Exec(ctx)
+	return evt, err
+}
+
+func (c *UserEventRedisCache) After(ctx context.Context, uid int64, id string) ([]domain.UserEvent, error) {
+	// ( makes the range exclusive
+	msgs, err := c.client.XRange(ctx, c.key(uid), "("+id, "+").Result()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.UserEvent, 0, len(msgs))
+	for _, msg := range msgs {
+		typ, _ := msg.Values["type"].(string)
+		data, _ := msg.Values["data"].(string)
+		res = append(res, domain.UserEvent{
+			Id:   msg.ID,
+			Uid:  uid,
+			Type: typ,
+			Data: data,
+		})
+	}
+	return res, nil
+}
+
+func (c *UserEventRedisCache) Subscribe(ctx context.Context) <-chan domain.UserEvent {
+	pubsub := c.client.Subscribe(ctx, c.channel)
+	res := make(chan domain.UserEvent, 1024)
+	go func() {
+		defer close(res)
+		defer pubsub.Close()
+		// the channel reconnects by itself when Redis is back
+		msgs := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				var evt domain.UserEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &evt); err != nil {
+					log.Println(err)
+					continue
+				}
+				res <- evt
+			}
+		}
+	}()
+	return res
+}
+
+func (c *UserEventRedisCache) key(uid int64) string {
+	return fmt.Sprintf("user_events:%d", uid)
+}
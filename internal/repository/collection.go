@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository/dao"
+	"time"
+)
+
+var (
+	ErrCollectionNotFound      = dao.ErrCollectionNotFound
+	ErrDuplicateCollectionName = dao.ErrDuplicateCollectionName
+)
+
+type CollectionRepository interface {
+	Create(ctx context.Context, c domain.Collection) (int64, error)
+	Update(ctx context.Context, c domain.Collection) error
+	Delete(ctx context.Context, uid int64, id int64) error
+	FindById(ctx context.Context, id int64) (domain.Collection, error)
+	FindByUid(ctx context.Context, uid int64, publicOnly bool, offset int, limit int) ([]domain.Collection, error)
+	// FindItems returns the ids of the resources in uid's collection cid, the latest collected first
+	FindItems(ctx context.Context, uid int64, cid int64, offset int, limit int) ([]int64, error)
+}
+
+type collectionRepository struct {
+	dao dao.CollectionDAO
+}
+
+func NewCollectionRepository(dao dao.CollectionDAO) CollectionRepository {
+	return &collectionRepository{
+		dao: dao,
+	}
+}
+
+func (repo *collectionRepository) Create(ctx context.Context, c domain.Collection) (int64, error) {
+	return repo.dao.Insert(ctx, repo.toEntity(c))
+}
+
+func (repo *collectionRepository) Update(ctx context.Context, c domain.Collection) error {
+	return repo.dao.Update(ctx, repo.toEntity(c))
+}
+
+func (repo *collectionRepository) Delete(ctx context.Context, uid int64, id int64) error {
+	return repo.dao.Delete(ctx, uid, id)
+}
+
+func (repo *collectionRepository) FindById(ctx context.Context, id int64) (domain.Collection, error) {
+	c, err := repo.dao.FindById(ctx, id)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	return repo.toDomain(c), nil
+}
+
+func (repo *collectionRepository) FindByUid(ctx context.Context, uid int64, publicOnly bool, offset int, limit int) ([]domain.Collection, error) {
+	cs, err := repo.dao.FindByUid(ctx, uid, publicOnly, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Collection, 0, len(cs))
+	for _, c := range cs {
+		res = append(res, repo.toDomain(c))
+	}
+	return res, nil
+}
+
+func (repo *collectionRepository) FindItems(ctx context.Context, uid int64, cid int64, offset int, limit int) ([]int64, error) {
+	items, err := repo.dao.FindItems(ctx, uid, cid, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]int64, 0, len(items))
+	for _, item := range items {
+		res = append(res, item.BizId)
+	}
+	return res, nil
+}
+
+func (repo *collectionRepository) toDomain(c dao.Collection) domain.Collection {
+	return domain.Collection{
+		Id:        c.Id,
+		Uid:       c.Uid,
+		Name:      c.Name,
+		Public:    c.Public,
+		CreatedAt: time.UnixMilli(c.CreatedAt),
+		UpdatedAt: time.UnixMilli(c.UpdatedAt),
+	}
+}
+
+func (repo *collectionRepository) toEntity(c domain.Collection) dao.Collection {
+	return dao.Collection{
+		Id:     c.Id,
+		Uid:    c.Uid,
+		Name:   c.Name,
+		Public: c.Public,
+	}
+}
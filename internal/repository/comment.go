@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository/cache"
+	"github.com/webook/internal/repository/dao"
+	"log"
+	"time"
+)
+
+var ErrCommentNotFound = dao.ErrCommentNotFound
+
+type CommentRepository interface {
+	Create(ctx context.Context, c domain.Comment) (int64, error)
+	GetById(ctx context.Context, id int64) (domain.Comment, error)
+	// Delete deletes the comment with all the replies to it
+	Delete(ctx context.Context, c domain.Comment) error
+	// FindRoots finds the top-level comments, each with its first replies
+	FindRoots(ctx context.Context, aid int64, offset int, limit int, replies int) ([]domain.Comment, error)
+	FindReplies(ctx context.Context, rootId int64, offset int, limit int) ([]domain.Comment, error)
+	Count(ctx context.Context, aid int64) (int64, error)
+}
+
+type CachedCommentRepository struct {
+	dao   dao.CommentDAO
+	cache cache.CommentCache
+}
+
+func NewCachedCommentRepository(dao dao.CommentDAO, c cache.CommentCache) CommentRepository {
+	return &CachedCommentRepository{
+		dao:   dao,
+		cache: c,
+	}
+}
+
+func (repo *CachedCommentRepository) Create(ctx context.Context, c domain.Comment) (int64, error) {
+	id, err := repo.dao.Insert(ctx, repo.toEntity(c))
+	if err != nil {
+		return 0, err
+	}
+	repo.delCount(ctx, c.ArticleId)
+	return id, nil
+}
+
+func (repo *CachedCommentRepository) GetById(ctx context.Context, id int64) (domain.Comment, error) {
+	c, err := repo.dao.GetById(ctx, id)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	return repo.toDomain(c), nil
+}
+
+func (repo *CachedCommentRepository) Delete(ctx context.Context, c domain.Comment) error {
+	_, err := repo.dao.Delete(ctx, c.Id)
+	if err != nil {
+		return err
+	}
+	repo.delCount(ctx, c.ArticleId)
+	return nil
+}
+
+func (repo *CachedCommentRepository) FindRoots(ctx context.Context, aid int64, offset int, limit int, replies int) ([]domain.Comment, error) {
+	roots, err := repo.dao.FindRoots(ctx, aid, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Comment, 0, len(roots))
+	for _, root := range roots {
+		c := repo.toDomain(root)
+		if replies > 0 {
+			// one query per thread, a page has a few dozens of threads at most
+			c.Replies, err = repo.FindReplies(ctx, c.Id, 0, replies)
+			if err != nil {
+				return nil, err
+			}
+		}
+		res = append(res, c)
+	}
+	return res, nil
+}
+
+func (repo *CachedCommentRepository) FindReplies(ctx context.Context, rootId int64, offset int, limit int) ([]domain.Comment, error) {
+	cs, err := repo.dao.FindReplies(ctx, rootId, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Comment, 0, len(cs))
+	for _, c := range cs {
+		res = append(res, repo.toDomain(c))
+	}
+	return res, nil
+}
+
+func (repo *CachedCommentRepository) Count(ctx context.Context, aid int64) (int64, error) {
+	cnt, err := repo.cache.GetCount(ctx, aid)
+	if err == nil {
+		return cnt, nil
+	}
+	cnt, err = repo.dao.CountByArticle(ctx, aid)
+	if err != nil {
+		return 0, err
+	}
+	if err = repo.cache.SetCount(ctx, aid, cnt); err != nil {
+		log.Println(err)
+	}
+	return cnt, nil
+}
+
+func (repo *CachedCommentRepository) delCount(ctx context.Context, aid int64) {
+	if err := repo.cache.DelCount(ctx, aid); err != nil {
+		// the count will be stale until it expires
+		log.Println(err)
+	}
+}
+
+func (repo *CachedCommentRepository) toDomain(c dao.Comment) domain.Comment {
+	return domain.Comment{
+		Id:        c.Id,
+		ArticleId: c.ArticleId,
+		Commentator: domain.Commentator{
+			Id: c.Uid,
+		},
+		Content:   c.Content,
+		RootId:    c.RootId,
+		ParentId:  c.ParentId,
+		CreatedAt: time.UnixMilli(c.CreatedAt),
+		UpdatedAt: time.UnixMilli(c.UpdatedAt),
+	}
+}
+
+func (repo *CachedCommentRepository) toEntity(c domain.Comment) dao.Comment {
+	return dao.Comment{
+		Id:        c.Id,
+		ArticleId: c.ArticleId,
+		Uid:       c.Commentator.Id,
+		Content:   c.Content,
+		RootId:    c.RootId,
+		ParentId:  c.ParentId,
+	}
+}
@@ -0,0 +1,337 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
+)
+
+var (
+	ErrArticleNotFound     = errors.New("Article doesn't exist or author doesn't match")
+	ErrArticleNotScheduled = errors.New("Article is not scheduled or not due")
+)
+
+type ArticleDAO interface {
+	Insert(ctx context.Context, art Article) (int64, error)
+	UpdateById(ctx context.Context, art Article) error
+	GetById(ctx context.Context, id int64) (Article, error)
+	Sync(ctx context.Context, art Article) (int64, error)
+	SyncStatus(ctx context.Context, uid int64, id int64, status uint8) error
+	GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error)
+	GetPubById(ctx context.Context, id int64) (PublishedArticle, error)
+	Schedule(ctx context.Context, uid int64, id int64, status uint8, publishAt int64) error
+	// CancelSchedule changes the status from scheduled to status
+	CancelSchedule(ctx context.Context, uid int64, id int64, scheduled uint8, status uint8) error
+	// FindScheduled finds the scheduled articles whose publish_at is not later than before
+	FindScheduled(ctx context.Context, scheduled uint8, before int64, limit int) ([]Article, error)
+	// PublishScheduled syncs the draft as published if it is scheduled and due by before,
+	// otherwise it returns ErrArticleNotScheduled
+	PublishScheduled(ctx context.Context, id int64, scheduled uint8, published uint8, before int64) (Article, error)
+	GetPubByTag(ctx context.Context, tag string, status uint8, offset int, limit int) ([]PublishedArticle, error)
+	// ListPub lists the published articles first published since the given time, ordered by id
+	ListPub(ctx context.Context, status uint8, since int64, offset int, limit int) ([]PublishedArticle, error)
+	// GetPubByAuthor finds the author's published articles, the most recently updated first
+	GetPubByAuthor(ctx context.Context, uid int64, status uint8, limit int) ([]PublishedArticle, error)
+	// ListLatestPub lists the latest published articles of all authors, the newest first
+	ListLatestPub(ctx context.Context, status uint8, limit int) ([]PublishedArticle, error)
+}
+
+type GORMArticleDAO struct {
+	db *gorm.DB
+}
+
+func NewArticleGORMDAO(db *gorm.DB) ArticleDAO {
+	return &GORMArticleDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMArticleDAO) Insert(ctx context.Context, art Article) (int64, error) {
+	now := time.Now().UnixMilli()
+	art.CreatedAt = now
+	art.UpdatedAt = now
+	err := dao.db.WithContext(ctx).Create(&art).Error
+	return art.Id, err
+}
+
+// UpdateById only updates the article when it belongs to art.AuthorId,
+// so nobody can modify other authors' articles by guessing the id
+func (dao *GORMArticleDAO) UpdateById(ctx context.Context, art Article) error {
+	res := dao.db.WithContext(ctx).Model(&Article{}).
+		Where("id=? AND author_id=?", art.Id, art.AuthorId).
+		Updates(map[string]any{
+			"title":      art.Title,
+			"content":    art.Content,
+			"format":     art.Format,
+			"category":   art.Category,
+			"tags":       art.Tags,
+			"status":     art.Status,
+			"updated_at": time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		// either the id is wrong, or someone is trying to edit other's article
+		return ErrArticleNotFound
+	}
+	return nil
+}
+
+func (dao *GORMArticleDAO) GetById(ctx context.Context, id int64) (Article, error) {
+	var art Article
+	err := dao.db.WithContext(ctx).Where("id=?", id).First(&art).Error
+	if err == gorm.ErrRecordNotFound {
+		return art, ErrArticleNotFound
+	}
+	return art, err
+}
+
+func (dao *GORMArticleDAO) GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error) {
+	var arts []Article
+	// hits the aid_utime index
+	err := dao.db.WithContext(ctx).
+		Where("author_id=?", uid).
+		Order("updated_at DESC").
+		Offset(offset).Limit(limit).
+		Find(&arts).Error
+	return arts, err
+}
+
+func (dao *GORMArticleDAO) GetPubById(ctx context.Context, id int64) (PublishedArticle, error) {
+	var art PublishedArticle
+	err := dao.db.WithContext(ctx).Where("id=?", id).First(&art).Error
+	if err == gorm.ErrRecordNotFound {
+		return art, ErrArticleNotFound
+	}
+	return art, err
+}
+
+func (dao *GORMArticleDAO) Schedule(ctx context.Context, uid int64, id int64, status uint8, publishAt int64) error {
+	res := dao.db.WithContext(ctx).Model(&Article{}).
+		Where("id=? AND author_id=?", id, uid).
+		Updates(map[string]any{
+			"status":     status,
+			"publish_at": publishAt,
+			"updated_at": time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrArticleNotFound
+	}
+	return nil
+}
+
+func (dao *GORMArticleDAO) CancelSchedule(ctx context.Context, uid int64, id int64, scheduled uint8, status uint8) error {
+	res := dao.db.WithContext(ctx).Model(&Article{}).
+		Where("id=? AND author_id=? AND status=?", id, uid, scheduled).
+		Updates(map[string]any{
+			"status":     status,
+			"publish_at": 0,
+			"updated_at": time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		// not found, or not scheduled at all
+		return ErrArticleNotFound
+	}
+	return nil
+}
+
+func (dao *GORMArticleDAO) FindScheduled(ctx context.Context, scheduled uint8, before int64, limit int) ([]Article, error) {
+	var arts []Article
+	// hits the status_publish_at index
+	err := dao.db.WithContext(ctx).
+		Where("status=? AND publish_at<=?", scheduled, before).
+		Order("publish_at").
+		Limit(limit).
+		Find(&arts).Error
+	return arts, err
+}
+
+// PublishScheduled locks the draft, so that it can't be cancelled or published twice meanwhile
+func (dao *GORMArticleDAO) PublishScheduled(ctx context.Context, id int64, scheduled uint8, published uint8, before int64) (Article, error) {
+	var art Article
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id=? AND status=? AND publish_at<=?", id, scheduled, before).
+			First(&art).Error
+		if err == gorm.ErrRecordNotFound {
+			return ErrArticleNotScheduled
+		}
+		if err != nil {
+			return err
+		}
+		art.Status = published
+		_, err = NewArticleGORMDAO(tx).Sync(ctx, art)
+		return err
+	})
+	return art, err
+}
+
+// Sync saves the draft and copies it into the published table in one transaction,
+// readers only see published_articles, so they never get a half-edited draft
+func (dao *GORMArticleDAO) Sync(ctx context.Context, art Article) (int64, error) {
+	id := art.Id
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		txDAO := NewArticleGORMDAO(tx)
+		if id > 0 {
+			err = txDAO.UpdateById(ctx, art)
+		} else {
+			id, err = txDAO.Insert(ctx, art)
+		}
+		if err != nil {
+			return err
+		}
+		art.Id = id
+		now := time.Now().UnixMilli()
+		pubArt := PublishedArticle(art)
+		pubArt.CreatedAt = now
+		pubArt.UpdatedAt = now
+		// upsert, republishing an article overwrites the previous published version
+		err = tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"title":      pubArt.Title,
+				"content":    pubArt.Content,
+				"format":     pubArt.Format,
+				"category":   pubArt.Category,
+				"tags":       pubArt.Tags,
+				"status":     pubArt.Status,
+				"updated_at": now,
+			}),
+		}).Create(&pubArt).Error
+		if err != nil {
+			return err
+		}
+		return dao.syncTags(tx, id, pubArt.Tags, now)
+	})
+	return id, err
+}
+
+// syncTags replaces the tags of the published article
+func (dao *GORMArticleDAO) syncTags(tx *gorm.DB, aid int64, tags string, now int64) error {
+	err := tx.Where("article_id=?", aid).Delete(&PublishedArticleTag{}).Error
+	if err != nil || tags == "" {
+		return err
+	}
+	names := strings.Split(tags, ",")
+	rows := make([]PublishedArticleTag, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, PublishedArticleTag{
+			ArticleId: aid,
+			Tag:       name,
+			CreatedAt: now,
+		})
+	}
+	return tx.Create(&rows).Error
+}
+
+// GetPubByTag finds the published articles with the tag, the most recently updated first
+func (dao *GORMArticleDAO) GetPubByTag(ctx context.Context, tag string, status uint8, offset int, limit int) ([]PublishedArticle, error) {
+	var arts []PublishedArticle
+	err := dao.db.WithContext(ctx).
+		Joins("JOIN published_article_tags ON published_article_tags.article_id = published_articles.id").
+		Where("published_article_tags.tag=? AND published_articles.status=?", tag, status).
+		Order("published_articles.updated_at DESC").
+		Offset(offset).Limit(limit).
+		Find(&arts).Error
+	return arts, err
+}
+
+func (dao *GORMArticleDAO) ListPub(ctx context.Context, status uint8, since int64, offset int, limit int) ([]PublishedArticle, error) {
+	var arts []PublishedArticle
+	err := dao.db.WithContext(ctx).
+		Where("status=? AND created_at>=?", status, since).
+		Order("id").
+		Offset(offset).Limit(limit).
+		Find(&arts).Error
+	return arts, err
+}
+
+func (dao *GORMArticleDAO) GetPubByAuthor(ctx context.Context, uid int64, status uint8, limit int) ([]PublishedArticle, error) {
+	var arts []PublishedArticle
+	// hits the aid_utime index
+	err := dao.db.WithContext(ctx).
+		Where("author_id=? AND status=?", uid, status).
+		Order("updated_at DESC").
+		Limit(limit).
+		Find(&arts).Error
+	return arts, err
+}
+
+func (dao *GORMArticleDAO) ListLatestPub(ctx context.Context, status uint8, limit int) ([]PublishedArticle, error) {
+	var arts []PublishedArticle
+	err := dao.db.WithContext(ctx).
+		Where("status=?", status).
+		Order("id DESC").
+		Limit(limit).
+		Find(&arts).Error
+	return arts, err
+}
+
+// SyncStatus updates the status in both author and reader tables
+func (dao *GORMArticleDAO) SyncStatus(ctx context.Context, uid int64, id int64, status uint8) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Article{}).
+			Where("id=? AND author_id=?", id, uid).
+			Updates(map[string]any{
+				"status":     status,
+				"updated_at": now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrArticleNotFound
+		}
+		// the article may never be published, so no row affected is fine here
+		return tx.Model(&PublishedArticle{}).
+			Where("id=?", id).
+			Updates(map[string]any{
+				"status":     status,
+				"updated_at": now,
+			}).Error
+	})
+}
+
+// Article is the author's version (draft) of an article
+type Article struct {
+	Id      int64  `gorm:"primaryKey,autoIncrement"`
+	Title   string `gorm:"type=varchar(4096)"`
+	Content string `gorm:"type=BLOB"`
+	Format  uint8
+	// author's list is ordered by updated_at, so <author_id, updated_at> is a composite index
+	AuthorId int64 `gorm:"index:aid_utime"`
+	// the publishing job looks for due articles by <status, publish_at>
+	Status    uint8  `gorm:"index:status_publish_at"`
+	PublishAt int64  `gorm:"index:status_publish_at"`
+	Category  string `gorm:"type=varchar(64)"`
+	// normalized tags separated by comma
+	Tags string `gorm:"type=varchar(1024)"`
+
+	// timezone，UTC 0 millisecond
+	CreatedAt int64
+	UpdatedAt int64 `gorm:"index:aid_utime"`
+}
+
+// PublishedArticle is the reader's version of an article
+type PublishedArticle Article
+
+// PublishedArticleTag is kept in sync with the tags of PublishedArticle,
+// so that readers can browse the published articles by tag
+type PublishedArticleTag struct {
+	Id        int64  `gorm:"primaryKey,autoIncrement"`
+	Tag       string `gorm:"type:varchar(64);uniqueIndex:tag_aid"`
+	ArticleId int64  `gorm:"uniqueIndex:tag_aid;index"`
+	CreatedAt int64
+}
@@ -0,0 +1,106 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"time"
+)
+
+var ErrRevisionNotFound = errors.New("Revision doesn't exist")
+
+type ArticleRevisionDAO interface {
+	// Insert saves rev as the newest revision of the article,
+	// and removes the oldest ones if there are more than maxRevisions
+	Insert(ctx context.Context, rev ArticleRevision, maxRevisions int) (int64, error)
+	GetByArticle(ctx context.Context, aid int64) ([]ArticleRevision, error)
+	GetByRevision(ctx context.Context, aid int64, revision int64) (ArticleRevision, error)
+}
+
+type GORMArticleRevisionDAO struct {
+	db *gorm.DB
+}
+
+func NewArticleRevisionGORMDAO(db *gorm.DB) ArticleRevisionDAO {
+	return &GORMArticleRevisionDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMArticleRevisionDAO) Insert(ctx context.Context, rev ArticleRevision, maxRevisions int) (int64, error) {
+	rev.CreatedAt = time.Now().UnixMilli()
+	const maxAttempts = 3
+	var err error
+	for i := 0; i < maxAttempts; i++ {
+		rev.Id = 0
+		rev.Revision, err = dao.insert(ctx, rev, maxRevisions)
+		me, ok := err.(*mysql.MySQLError)
+		const duplicateErr uint16 = 1062
+		if !ok || me.Number != duplicateErr {
+			break
+		}
+		// a concurrent save took the revision number, try the next one
+	}
+	return rev.Revision, err
+}
+
+func (dao *GORMArticleRevisionDAO) insert(ctx context.Context, rev ArticleRevision, maxRevisions int) (int64, error) {
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest int64
+		err := tx.Model(&ArticleRevision{}).
+			Select("COALESCE(MAX(revision), 0)").
+			Where("article_id=?", rev.ArticleId).
+			Scan(&latest).Error
+		if err != nil {
+			return err
+		}
+		// concurrent saves conflict on the aid_revision unique index
+		rev.Revision = latest + 1
+		err = tx.Create(&rev).Error
+		if err != nil {
+			return err
+		}
+		if maxRevisions <= 0 {
+			// keep everything
+			return nil
+		}
+		return tx.Where("article_id=? AND revision<=?", rev.ArticleId, rev.Revision-int64(maxRevisions)).
+			Delete(&ArticleRevision{}).Error
+	})
+	return rev.Revision, err
+}
+
+// GetByArticle lists the revisions without content, the newest first
+func (dao *GORMArticleRevisionDAO) GetByArticle(ctx context.Context, aid int64) ([]ArticleRevision, error) {
+	var revs []ArticleRevision
+	err := dao.db.WithContext(ctx).
+		Select("id", "article_id", "revision", "title", "created_at").
+		Where("article_id=?", aid).
+		Order("revision DESC").
+		Find(&revs).Error
+	return revs, err
+}
+
+func (dao *GORMArticleRevisionDAO) GetByRevision(ctx context.Context, aid int64, revision int64) (ArticleRevision, error) {
+	var rev ArticleRevision
+	err := dao.db.WithContext(ctx).
+		Where("article_id=? AND revision=?", aid, revision).
+		First(&rev).Error
+	if err == gorm.ErrRecordNotFound {
+		return rev, ErrRevisionNotFound
+	}
+	return rev, err
+}
+
+// ArticleRevision never changes once inserted
+type ArticleRevision struct {
+	Id        int64  `gorm:"primaryKey,autoIncrement"`
+	ArticleId int64  `gorm:"uniqueIndex:aid_revision"`
+	Revision  int64  `gorm:"uniqueIndex:aid_revision"`
+	Title     string `gorm:"type=varchar(4096)"`
+	Content   string `gorm:"type=BLOB"`
+
+	// timezone，UTC 0 millisecond
+	CreatedAt int64
+}
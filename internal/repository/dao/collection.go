@@ -0,0 +1,137 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"time"
+)
+
+var (
+	ErrCollectionNotFound      = errors.New("Collection doesn't exist")
+	ErrDuplicateCollectionName = errors.New("Collection name exists already")
+)
+
+type CollectionDAO interface {
+	Insert(ctx context.Context, c Collection) (int64, error)
+	// Update updates the name and visibility, it returns ErrCollectionNotFound if the collection isn't c.Uid's
+	Update(ctx context.Context, c Collection) error
+	// Delete moves the items of the collection into the default one
+	Delete(ctx context.Context, uid int64, id int64) error
+	FindById(ctx context.Context, id int64) (Collection, error)
+	// FindByUid finds uid's collections, the latest created first
+	FindByUid(ctx context.Context, uid int64, publicOnly bool, offset int, limit int) ([]Collection, error)
+	// FindItems finds the items in uid's collection cid, the latest collected first
+	FindItems(ctx context.Context, uid int64, cid int64, offset int, limit int) ([]UserCollectionBiz, error)
+}
+
+type GORMCollectionDAO struct {
+	db *gorm.DB
+}
+
+func NewCollectionGORMDAO(db *gorm.DB) CollectionDAO {
+	return &GORMCollectionDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMCollectionDAO) Insert(ctx context.Context, c Collection) (int64, error) {
+	now := time.Now().UnixMilli()
+	c.CreatedAt = now
+	c.UpdatedAt = now
+	err := dao.db.WithContext(ctx).Create(&c).Error
+	return c.Id, dao.checkDuplicate(err)
+}
+
+func (dao *GORMCollectionDAO) Update(ctx context.Context, c Collection) error {
+	res := dao.db.WithContext(ctx).Model(&Collection{}).
+		Where("id=? AND uid=?", c.Id, c.Uid).
+		Updates(map[string]any{
+			"name":       c.Name,
+			"public":     c.Public,
+			"updated_at": time.Now().UnixMilli(),
+		})
+	if err := dao.checkDuplicate(res.Error); err != nil {
+		return err
+	}
+	if res.RowsAffected == 0 {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
+
+func (dao *GORMCollectionDAO) Delete(ctx context.Context, uid int64, id int64) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id=? AND uid=?", id, uid).Delete(&Collection{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrCollectionNotFound
+		}
+		// the articles stay collected
+		return tx.Model(&UserCollectionBiz{}).
+			Where("uid=? AND cid=?", uid, id).
+			Updates(map[string]any{
+				"cid":        0,
+				"updated_at": time.Now().UnixMilli(),
+			}).Error
+	})
+}
+
+func (dao *GORMCollectionDAO) FindById(ctx context.Context, id int64) (Collection, error) {
+	var c Collection
+	err := dao.db.WithContext(ctx).Where("id=?", id).First(&c).Error
+	if err == gorm.ErrRecordNotFound {
+		return c, ErrCollectionNotFound
+	}
+	return c, err
+}
+
+func (dao *GORMCollectionDAO) FindByUid(ctx context.Context, uid int64, publicOnly bool, offset int, limit int) ([]Collection, error) {
+	var res []Collection
+	query := dao.db.WithContext(ctx).Where("uid=?", uid)
+	if publicOnly {
+		query = query.Where("public=?", true)
+	}
+	err := query.Order("id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMCollectionDAO) FindItems(ctx context.Context, uid int64, cid int64, offset int, limit int) ([]UserCollectionBiz, error) {
+	var res []UserCollectionBiz
+	// hits the uid_cid index
+	err := dao.db.WithContext(ctx).
+		Where("uid=? AND cid=?", uid, cid).
+		Order("id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMCollectionDAO) checkDuplicate(err error) error {
+	if me, ok := err.(*mysql.MySQLError); ok {
+		const duplicateErr uint16 = 1062
+		if me.Number == duplicateErr {
+			return ErrDuplicateCollectionName
+		}
+	}
+	return err
+}
+
+// Collection is a named folder of UserCollectionBiz
+type Collection struct {
+	Id     int64  `gorm:"primaryKey,autoIncrement"`
+	Uid    int64  `gorm:"uniqueIndex:uid_name"`
+	Name   string `gorm:"type:varchar(128);uniqueIndex:uid_name"`
+	Public bool
+
+	// timezone，UTC 0 millisecond
+	CreatedAt int64
+	UpdatedAt int64
+}
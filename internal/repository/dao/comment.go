@@ -0,0 +1,123 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+var ErrCommentNotFound = errors.New("Comment doesn't exist")
+
+type CommentDAO interface {
+	Insert(ctx context.Context, c Comment) (int64, error)
+	GetById(ctx context.Context, id int64) (Comment, error)
+	// Delete deletes the comment and all the replies to it, directly or not,
+	// returns how many comments are deleted
+	Delete(ctx context.Context, id int64) (int64, error)
+	// FindRoots finds the top-level comments of the article, the newest first
+	FindRoots(ctx context.Context, aid int64, offset int, limit int) ([]Comment, error)
+	// FindReplies finds the replies in the thread of rootId, the oldest first
+	FindReplies(ctx context.Context, rootId int64, offset int, limit int) ([]Comment, error)
+	CountByArticle(ctx context.Context, aid int64) (int64, error)
+}
+
+type GORMCommentDAO struct {
+	db *gorm.DB
+}
+
+func NewCommentGORMDAO(db *gorm.DB) CommentDAO {
+	return &GORMCommentDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMCommentDAO) Insert(ctx context.Context, c Comment) (int64, error) {
+	now := time.Now().UnixMilli()
+	c.CreatedAt = now
+	c.UpdatedAt = now
+	err := dao.db.WithContext(ctx).Create(&c).Error
+	return c.Id, err
+}
+
+func (dao *GORMCommentDAO) GetById(ctx context.Context, id int64) (Comment, error) {
+	var c Comment
+	err := dao.db.WithContext(ctx).Where("id=?", id).First(&c).Error
+	if err == gorm.ErrRecordNotFound {
+		return c, ErrCommentNotFound
+	}
+	return c, err
+}
+
+func (dao *GORMCommentDAO) Delete(ctx context.Context, id int64) (int64, error) {
+	var deleted int64
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ids := []int64{id}
+		// walks down the reply tree level by level, threads are not deep in practice
+		for frontier := ids; len(frontier) > 0; {
+			var children []int64
+			err := tx.Model(&Comment{}).
+				Where("parent_id IN ?", frontier).
+				Pluck("id", &children).Error
+			if err != nil {
+				return err
+			}
+			ids = append(ids, children...)
+			frontier = children
+		}
+		res := tx.Where("id IN ?", ids).Delete(&Comment{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrCommentNotFound
+		}
+		deleted = res.RowsAffected
+		return nil
+	})
+	return deleted, err
+}
+
+func (dao *GORMCommentDAO) FindRoots(ctx context.Context, aid int64, offset int, limit int) ([]Comment, error) {
+	var res []Comment
+	// hits the aid_root index
+	err := dao.db.WithContext(ctx).
+		Where("article_id=? AND root_id=?", aid, 0).
+		Order("id DESC").
+		Offset(offset).Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMCommentDAO) FindReplies(ctx context.Context, rootId int64, offset int, limit int) ([]Comment, error) {
+	var res []Comment
+	err := dao.db.WithContext(ctx).
+		Where("root_id=?", rootId).
+		Order("id").
+		Offset(offset).Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMCommentDAO) CountByArticle(ctx context.Context, aid int64) (int64, error) {
+	var cnt int64
+	err := dao.db.WithContext(ctx).Model(&Comment{}).
+		Where("article_id=?", aid).
+		Count(&cnt).Error
+	return cnt, err
+}
+
+// Comment is a comment on an article, RootId is 0 for top-level comments
+type Comment struct {
+	Id int64 `gorm:"primaryKey,autoIncrement"`
+	// top-level comments are listed by <article_id, root_id>
+	ArticleId int64 `gorm:"index:aid_root"`
+	Uid       int64
+	Content   string `gorm:"type:varchar(4096)"`
+	RootId    int64  `gorm:"index:aid_root;index"`
+	ParentId  int64  `gorm:"index"`
+
+	// timezone，UTC 0 millisecond
+	CreatedAt int64
+	UpdatedAt int64
+}
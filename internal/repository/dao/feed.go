@@ -0,0 +1,90 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+)
+
+type FeedDAO interface {
+	// CreatePushEvents writes the events into the inboxes of the followers
+	CreatePushEvents(ctx context.Context, events []FeedPushEvent) error
+	CreatePullEvent(ctx context.Context, event FeedPullEvent) error
+	// FindPushEvents finds the events in uid's inbox created before the time, the newest first
+	FindPushEvents(ctx context.Context, uid int64, before int64, limit int) ([]FeedPushEvent, error)
+	// FindPullEvents finds the events of the authors created before the time, the newest first
+	FindPullEvents(ctx context.Context, authorIds []int64, before int64, limit int) ([]FeedPullEvent, error)
+	// DeletePushEvents removes the events of the author from uid's inbox
+	DeletePushEvents(ctx context.Context, uid int64, authorId int64) error
+}
+
+type GORMFeedDAO struct {
+	db *gorm.DB
+}
+
+func NewFeedGORMDAO(db *gorm.DB) FeedDAO {
+	return &GORMFeedDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMFeedDAO) CreatePushEvents(ctx context.Context, events []FeedPushEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return dao.db.WithContext(ctx).Create(&events).Error
+}
+
+func (dao *GORMFeedDAO) CreatePullEvent(ctx context.Context, event FeedPullEvent) error {
+	return dao.db.WithContext(ctx).Create(&event).Error
+}
+
+func (dao *GORMFeedDAO) FindPushEvents(ctx context.Context, uid int64, before int64, limit int) ([]FeedPushEvent, error) {
+	var res []FeedPushEvent
+	// hits the uid_ctime index
+	err := dao.db.WithContext(ctx).
+		Where("uid=? AND ctime<?", uid, before).
+		Order("ctime DESC").
+		Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMFeedDAO) FindPullEvents(ctx context.Context, authorIds []int64, before int64, limit int) ([]FeedPullEvent, error) {
+	if len(authorIds) == 0 {
+		return nil, nil
+	}
+	var res []FeedPullEvent
+	// hits the aid_ctime index
+	err := dao.db.WithContext(ctx).
+		Where("author_id IN ? AND ctime<?", authorIds, before).
+		Order("ctime DESC").
+		Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMFeedDAO) DeletePushEvents(ctx context.Context, uid int64, authorId int64) error {
+	return dao.db.WithContext(ctx).
+		Where("uid=? AND author_id=?", uid, authorId).
+		Delete(&FeedPushEvent{}).Error
+}
+
+// FeedPushEvent is an article in the inbox of Uid, written when an author with few followers publishes
+type FeedPushEvent struct {
+	Id        int64 `gorm:"primaryKey,autoIncrement"`
+	Uid       int64 `gorm:"index:uid_ctime"`
+	AuthorId  int64
+	ArticleId int64
+	// timezone，UTC 0 millisecond
+	Ctime int64 `gorm:"index:uid_ctime"`
+}
+
+// FeedPullEvent is an article of an author with many followers,
+// the followers pull it when reading their feeds
+type FeedPullEvent struct {
+	Id        int64 `gorm:"primaryKey,autoIncrement"`
+	AuthorId  int64 `gorm:"index:aid_ctime"`
+	ArticleId int64
+	// timezone，UTC 0 millisecond
+	Ctime int64 `gorm:"index:aid_ctime"`
+}
@@ -0,0 +1,102 @@
+package dao
+
+import (
+	"context"
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"time"
+)
+
+type FollowDAO interface {
+	// Insert returns false if the follower follows the followee already
+	Insert(ctx context.Context, r FollowRelation) (bool, error)
+	// Delete returns false if the follower didn't follow the followee
+	Delete(ctx context.Context, follower int64, followee int64) (bool, error)
+	// FindFollowees finds who the follower follows, the latest followed first
+	FindFollowees(ctx context.Context, follower int64, offset int, limit int) ([]FollowRelation, error)
+	// FindFollowers finds who follows the followee, the latest followed first
+	FindFollowers(ctx context.Context, followee int64, offset int, limit int) ([]FollowRelation, error)
+	CountFollowers(ctx context.Context, followee int64) (int64, error)
+	CountFollowees(ctx context.Context, follower int64) (int64, error)
+}
+
+type GORMFollowDAO struct {
+	db *gorm.DB
+}
+
+func NewFollowGORMDAO(db *gorm.DB) FollowDAO {
+	return &GORMFollowDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMFollowDAO) Insert(ctx context.Context, r FollowRelation) (bool, error) {
+	now := time.Now().UnixMilli()
+	r.CreatedAt = now
+	r.UpdatedAt = now
+	err := dao.db.WithContext(ctx).Create(&r).Error
+	if me, ok := err.(*mysql.MySQLError); ok {
+		const duplicateErr uint16 = 1062
+		if me.Number == duplicateErr {
+			// following already
+			return false, nil
+		}
+	}
+	return err == nil, err
+}
+
+func (dao *GORMFollowDAO) Delete(ctx context.Context, follower int64, followee int64) (bool, error) {
+	res := dao.db.WithContext(ctx).
+		Where("follower=? AND followee=?", follower, followee).
+		Delete(&FollowRelation{})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (dao *GORMFollowDAO) FindFollowees(ctx context.Context, follower int64, offset int, limit int) ([]FollowRelation, error) {
+	var res []FollowRelation
+	// hits the follower_followee index
+	err := dao.db.WithContext(ctx).
+		Where("follower=?", follower).
+		Order("id DESC").
+		Offset(offset).Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMFollowDAO) FindFollowers(ctx context.Context, followee int64, offset int, limit int) ([]FollowRelation, error) {
+	var res []FollowRelation
+	// hits the followee index
+	err := dao.db.WithContext(ctx).
+		Where("followee=?", followee).
+		Order("id DESC").
+		Offset(offset).Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMFollowDAO) CountFollowers(ctx context.Context, followee int64) (int64, error) {
+	var cnt int64
+	err := dao.db.WithContext(ctx).Model(&FollowRelation{}).
+		Where("followee=?", followee).
+		Count(&cnt).Error
+	return cnt, err
+}
+
+func (dao *GORMFollowDAO) CountFollowees(ctx context.Context, follower int64) (int64, error) {
+	var cnt int64
+	err := dao.db.WithContext(ctx).Model(&FollowRelation{}).
+		Where("follower=?", follower).
+		Count(&cnt).Error
+	return cnt, err
+}
+
+// FollowRelation is who follows whom, unfollowing deletes the row
+type FollowRelation struct {
+	Id       int64 `gorm:"primaryKey,autoIncrement"`
+	Follower int64 `gorm:"uniqueIndex:follower_followee"`
+	Followee int64 `gorm:"uniqueIndex:follower_followee;index"`
+
+	// timezone，UTC 0 millisecond
+	CreatedAt int64
+	UpdatedAt int64
+}
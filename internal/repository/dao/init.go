@@ -1,8 +1,62 @@
 package dao
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"time"
+)
 
 func InitTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(&Migration{}); err != nil {
+		return err
+	}
+	// the accounts created before email verification existed are trusted,
+	// the rollout is recorded before adding the column so that a failed backfill is retried
+	if !db.Migrator().HasColumn(&User{}, "EmailVerified") {
+		err := db.Where(Migration{Name: migrationEmailVerified}).
+			FirstOrCreate(&Migration{Name: migrationEmailVerified, CreatedAt: time.Now().UnixMilli()}).Error
+		if err != nil {
+			return err
+		}
+	}
 	//	subject to change
-	return db.AutoMigrate(&User{})
+	err := db.AutoMigrate(&User{}, &Article{}, &PublishedArticle{}, &PublishedArticleTag{}, &ArticleRevision{},
+		&Interactive{}, &UserLikeBiz{}, &UserCollectionBiz{}, &Comment{}, &FollowRelation{},
+		&FeedPushEvent{}, &FeedPullEvent{}, &Notification{}, &Collection{})
+	if err != nil {
+		return err
+	}
+	return backfillEmailVerified(db)
+}
+
+const migrationEmailVerified = "backfill_email_verified"
+
+// backfillEmailVerified verifies the email accounts created before the rollout,
+// it is a no-op once done
+func backfillEmailVerified(db *gorm.DB) error {
+	var m Migration
+	err := db.Where("name=?", migrationEmailVerified).First(&m).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil || m.Done {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&User{}).
+			Where("email IS NOT NULL AND created_at<?", m.CreatedAt).
+			Update("email_verified", true).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&m).Update("done", true).Error
+	})
+}
+
+// Migration records the data migrations, Done is set once it succeeds
+type Migration struct {
+	Name string `gorm:"primaryKey;type:varchar(128)"`
+	Done bool
+
+	// timezone，UTC 0 millisecond
+	CreatedAt int64
 }
@@ -0,0 +1,253 @@
+package dao
+
+import (
+	"context"
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type InteractiveDAO interface {
+	// IncrReadCnt increases the total read count, and the unique read count as well if unique is true
+	IncrReadCnt(ctx context.Context, biz string, bizId int64, unique bool) error
+	// InsertLikeInfo returns false if the user liked it already
+	InsertLikeInfo(ctx context.Context, biz string, bizId int64, uid int64) (bool, error)
+	// DeleteLikeInfo returns false if the user didn't like it
+	DeleteLikeInfo(ctx context.Context, biz string, bizId int64, uid int64) (bool, error)
+	// InsertCollectionBiz returns false if the user collected it already,
+	// it is moved into the collection cid then
+	InsertCollectionBiz(ctx context.Context, biz string, bizId int64, uid int64, cid int64) (bool, error)
+	// DeleteCollectionBiz returns false if the user didn't collect it
+	DeleteCollectionBiz(ctx context.Context, biz string, bizId int64, uid int64) (bool, error)
+	GetLikeInfo(ctx context.Context, biz string, bizId int64, uid int64) (UserLikeBiz, error)
+	GetCollectionInfo(ctx context.Context, biz string, bizId int64, uid int64) (UserCollectionBiz, error)
+	Get(ctx context.Context, biz string, bizId int64) (Interactive, error)
+	// GetByIds skips the ids nobody has interacted with
+	GetByIds(ctx context.Context, biz string, ids []int64) ([]Interactive, error)
+}
+
+type GORMInteractiveDAO struct {
+	db *gorm.DB
+}
+
+func NewInteractiveGORMDAO(db *gorm.DB) InteractiveDAO {
+	return &GORMInteractiveDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMInteractiveDAO) IncrReadCnt(ctx context.Context, biz string, bizId int64, unique bool) error {
+	if unique {
+		return dao.incrCnt(dao.db.WithContext(ctx), biz, bizId, 1, "read_cnt", "unique_read_cnt")
+	}
+	return dao.incrCnt(dao.db.WithContext(ctx), biz, bizId, 1, "read_cnt")
+}
+
+func (dao *GORMInteractiveDAO) InsertLikeInfo(ctx context.Context, biz string, bizId int64, uid int64) (bool, error) {
+	var changed bool
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UnixMilli()
+		// upsert, unlike only marks the row as not liked.
+		// updated_at is assigned before status so that it sees the old status,
+		// a row liked already is left unchanged and affects no rows
+		res := tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Set{
+				{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("IF(status=1, updated_at, ?)", now)},
+				{Column: clause.Column{Name: "status"}, Value: 1},
+			},
+		}).Create(&UserLikeBiz{
+			Uid:       uid,
+			Biz:       biz,
+			BizId:     bizId,
+			Status:    1,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		// 1 for a new row, 2 for liking again, 0 for liked already
+		changed = res.RowsAffected > 0
+		if !changed {
+			return nil
+		}
+		return dao.incrCnt(tx, biz, bizId, 1, "like_cnt")
+	})
+	return changed, err
+}
+
+func (dao *GORMInteractiveDAO) DeleteLikeInfo(ctx context.Context, biz string, bizId int64, uid int64) (bool, error) {
+	var changed bool
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&UserLikeBiz{}).
+			Where("uid=? AND biz=? AND biz_id=? AND status=?", uid, biz, bizId, 1).
+			Updates(map[string]any{
+				"status":     0,
+				"updated_at": time.Now().UnixMilli(),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		changed = res.RowsAffected > 0
+		if !changed {
+			return nil
+		}
+		return dao.incrCnt(tx, biz, bizId, -1, "like_cnt")
+	})
+	return changed, err
+}
+
+func (dao *GORMInteractiveDAO) InsertCollectionBiz(ctx context.Context, biz string, bizId int64, uid int64, cid int64) (bool, error) {
+	var changed bool
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UnixMilli()
+		err := tx.Create(&UserCollectionBiz{
+			Uid:       uid,
+			Biz:       biz,
+			BizId:     bizId,
+			Cid:       cid,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}).Error
+		if me, ok := err.(*mysql.MySQLError); ok {
+			const duplicateErr uint16 = 1062
+			if me.Number == duplicateErr {
+				// collected already, move it
+				return tx.Model(&UserCollectionBiz{}).
+					Where("uid=? AND biz=? AND biz_id=? AND cid<>?", uid, biz, bizId, cid).
+					Updates(map[string]any{
+						"cid":        cid,
+						"updated_at": now,
+					}).Error
+			}
+		}
+		if err != nil {
+			return err
+		}
+		changed = true
+		return dao.incrCnt(tx, biz, bizId, 1, "collect_cnt")
+	})
+	return changed, err
+}
+
+func (dao *GORMInteractiveDAO) DeleteCollectionBiz(ctx context.Context, biz string, bizId int64, uid int64) (bool, error) {
+	var changed bool
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("uid=? AND biz=? AND biz_id=?", uid, biz, bizId).
+			Delete(&UserCollectionBiz{})
+		if res.Error != nil {
+			return res.Error
+		}
+		changed = res.RowsAffected > 0
+		if !changed {
+			return nil
+		}
+		return dao.incrCnt(tx, biz, bizId, -1, "collect_cnt")
+	})
+	return changed, err
+}
+
+func (dao *GORMInteractiveDAO) GetLikeInfo(ctx context.Context, biz string, bizId int64, uid int64) (UserLikeBiz, error) {
+	var res UserLikeBiz
+	err := dao.db.WithContext(ctx).
+		Where("uid=? AND biz=? AND biz_id=? AND status=?", uid, biz, bizId, 1).
+		First(&res).Error
+	return res, err
+}
+
+func (dao *GORMInteractiveDAO) GetCollectionInfo(ctx context.Context, biz string, bizId int64, uid int64) (UserCollectionBiz, error) {
+	var res UserCollectionBiz
+	err := dao.db.WithContext(ctx).
+		Where("uid=? AND biz=? AND biz_id=?", uid, biz, bizId).
+		First(&res).Error
+	return res, err
+}
+
+func (dao *GORMInteractiveDAO) Get(ctx context.Context, biz string, bizId int64) (Interactive, error) {
+	var res Interactive
+	err := dao.db.WithContext(ctx).
+		Where("biz=? AND biz_id=?", biz, bizId).
+		First(&res).Error
+	return res, err
+}
+
+func (dao *GORMInteractiveDAO) GetByIds(ctx context.Context, biz string, ids []int64) ([]Interactive, error) {
+	var res []Interactive
+	err := dao.db.WithContext(ctx).
+		Where("biz=? AND biz_id IN ?", biz, ids).
+		Find(&res).Error
+	return res, err
+}
+
+// incrCnt adds delta to the columns, the row is created on the first interaction
+func (dao *GORMInteractiveDAO) incrCnt(tx *gorm.DB, biz string, bizId int64, delta int64, columns ...string) error {
+	now := time.Now().UnixMilli()
+	intr := Interactive{
+		Biz:       biz,
+		BizId:     bizId,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	updates := map[string]any{
+		"updated_at": now,
+	}
+	for _, column := range columns {
+		switch column {
+		case "read_cnt":
+			intr.ReadCnt = delta
+		case "unique_read_cnt":
+			intr.UniqueReadCnt = delta
+		case "like_cnt":
+			intr.LikeCnt = delta
+		case "collect_cnt":
+			intr.CollectCnt = delta
+		}
+		updates[column] = gorm.Expr(column+" + ?", delta)
+	}
+	return tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(updates),
+	}).Create(&intr).Error
+}
+
+// Interactive is the counters of a resource
+type Interactive struct {
+	Id            int64  `gorm:"primaryKey,autoIncrement"`
+	BizId         int64  `gorm:"uniqueIndex:biz_type_id"`
+	Biz           string `gorm:"type:varchar(128);uniqueIndex:biz_type_id"`
+	ReadCnt       int64
+	UniqueReadCnt int64 // each reader is counted once in every dedup window
+	LikeCnt       int64
+	CollectCnt    int64
+
+	// timezone，UTC 0 millisecond
+	CreatedAt int64
+	UpdatedAt int64
+}
+
+// UserLikeBiz is who likes what, Status 1 means liked, 0 means unliked
+type UserLikeBiz struct {
+	Id     int64  `gorm:"primaryKey,autoIncrement"`
+	Uid    int64  `gorm:"uniqueIndex:uid_biz_type_id"`
+	BizId  int64  `gorm:"uniqueIndex:uid_biz_type_id"`
+	Biz    string `gorm:"type:varchar(128);uniqueIndex:uid_biz_type_id"`
+	Status uint8
+
+	// timezone，UTC 0 millisecond
+	CreatedAt int64
+	UpdatedAt int64
+}
+
+// UserCollectionBiz is who collects what, into which Collection.
+// Cid 0 is the default collection, a resource is in one collection of the user at most
+type UserCollectionBiz struct {
+	Id    int64  `gorm:"primaryKey,autoIncrement"`
+	Uid   int64  `gorm:"uniqueIndex:uid_biz_type_id;index:uid_cid"`
+	BizId int64  `gorm:"uniqueIndex:uid_biz_type_id"`
+	Biz   string `gorm:"type:varchar(128);uniqueIndex:uid_biz_type_id"`
+	Cid   int64  `gorm:"index:uid_cid"`
+
+	// timezone，UTC 0 millisecond
+	CreatedAt int64
+	UpdatedAt int64
+}
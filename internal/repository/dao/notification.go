@@ -0,0 +1,110 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+const (
+	NotificationStatusUnread uint8 = iota
+	NotificationStatusRead
+)
+
+type NotificationDAO interface {
+	// Upsert collapses n into the unread notification of the same kind created since the given time,
+	// or inserts it if there is none
+	Upsert(ctx context.Context, n Notification, since int64) error
+	// FindByUid finds the notifications of uid, the latest updated first
+	FindByUid(ctx context.Context, uid int64, offset int, limit int) ([]Notification, error)
+	CountUnread(ctx context.Context, uid int64) (int64, error)
+	// MarkRead only marks uid's own notification, marking a read one is a no-op
+	MarkRead(ctx context.Context, uid int64, id int64) error
+	MarkAllRead(ctx context.Context, uid int64) error
+}
+
+type GORMNotificationDAO struct {
+	db *gorm.DB
+}
+
+func NewNotificationGORMDAO(db *gorm.DB) NotificationDAO {
+	return &GORMNotificationDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMNotificationDAO) Upsert(ctx context.Context, n Notification, since int64) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UnixMilli()
+		var cur Notification
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("uid=? AND kind=? AND status=? AND created_at>=?", n.Uid, n.Kind, NotificationStatusUnread, since).
+			Order("id DESC").
+			First(&cur).Error
+		switch err {
+		case nil:
+			return tx.Model(&cur).Updates(map[string]any{
+				"content":    n.Content,
+				"count":      gorm.Expr("count + 1"),
+				"updated_at": now,
+			}).Error
+		case gorm.ErrRecordNotFound:
+			n.Count = 1
+			n.Status = NotificationStatusUnread
+			n.CreatedAt = now
+			n.UpdatedAt = now
+			return tx.Create(&n).Error
+		default:
+			return err
+		}
+	})
+}
+
+func (dao *GORMNotificationDAO) FindByUid(ctx context.Context, uid int64, offset int, limit int) ([]Notification, error) {
+	var res []Notification
+	// hits the uid_utime index
+	err := dao.db.WithContext(ctx).
+		Where("uid=?", uid).
+		Order("updated_at DESC").
+		Offset(offset).Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMNotificationDAO) CountUnread(ctx context.Context, uid int64) (int64, error) {
+	var cnt int64
+	err := dao.db.WithContext(ctx).Model(&Notification{}).
+		Where("uid=? AND status=?", uid, NotificationStatusUnread).
+		Count(&cnt).Error
+	return cnt, err
+}
+
+func (dao *GORMNotificationDAO) MarkRead(ctx context.Context, uid int64, id int64) error {
+	return dao.db.WithContext(ctx).Model(&Notification{}).
+		Where("id=? AND uid=?", id, uid).
+		// updated_at is when the latest event came, the list is ordered by it
+		Update("status", NotificationStatusRead).Error
+}
+
+func (dao *GORMNotificationDAO) MarkAllRead(ctx context.Context, uid int64) error {
+	return dao.db.WithContext(ctx).Model(&Notification{}).
+		Where("uid=? AND status=?", uid, NotificationStatusUnread).
+		// updated_at is when the latest event came, the list is ordered by it
+		Update("status", NotificationStatusRead).Error
+}
+
+// Notification is an entry in the notification center of Uid
+type Notification struct {
+	Id      int64  `gorm:"primaryKey,autoIncrement"`
+	Uid     int64  `gorm:"index:uid_utime"`
+	Kind    string `gorm:"type:varchar(64)"`
+	Content string `gorm:"type:varchar(1024)"`
+	Count   int64
+	// read is a reserved word in MySQL, so it is a status
+	Status uint8
+
+	// timezone，UTC 0 millisecond
+	CreatedAt int64
+	UpdatedAt int64 `gorm:"index:uid_utime"`
+}
@@ -10,8 +10,10 @@ import (
 )
 
 var (
-	ErrDuplicateEmail = errors.New("Email Already Exists")
-	ErrRecordNotFound = gorm.ErrRecordNotFound
+	ErrDuplicateEmail  = errors.New("Email Already Exists")
+	ErrDuplicatePhone  = errors.New("Phone is bound to another account")
+	ErrDuplicateWechat = errors.New("Wechat is bound to another account")
+	ErrRecordNotFound  = gorm.ErrRecordNotFound
 )
 
 type UserDAO interface {
@@ -19,8 +21,20 @@ type UserDAO interface {
 	FindByEmail(ctx context.Context, email string) (User, error)
 	UpdateById(ctx context.Context, entity User) error
 	FindById(ctx context.Context, uid int64) (User, error)
+	// FindByIds skips the ids that don't exist
+	FindByIds(ctx context.Context, uids []int64) ([]User, error)
 	FindByPhone(ctx context.Context, phone string) (User, error)
 	FindByWechat(ctx context.Context, openId string) (User, error)
+	// UpdatePassword saves the hashed password
+	UpdatePassword(ctx context.Context, uid int64, password string) error
+	UpdateEmailVerified(ctx context.Context, uid int64, verified bool) error
+	// UpdatePhone returns ErrDuplicatePhone if another user has the phone
+	UpdatePhone(ctx context.Context, uid int64, phone string) error
+	// UpdateWechat returns ErrDuplicateWechat if another user has the openId,
+	// passing the invalid values clears the binding
+	UpdateWechat(ctx context.Context, uid int64, openId sql.NullString, unionId sql.NullString) error
+	// List lists the users ordered by id, it is for batch jobs
+	List(ctx context.Context, offset int, limit int) ([]User, error)
 }
 
 type GORMUserDAO struct {
@@ -73,6 +87,24 @@ func (dao *GORMUserDAO) FindById(ctx context.Context, uid int64) (User, error) {
 	return u, err
 }
 
+func (dao *GORMUserDAO) FindByIds(ctx context.Context, uids []int64) ([]User, error) {
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	var res []User
+	err := dao.db.WithContext(ctx).Where("id IN ?", uids).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMUserDAO) List(ctx context.Context, offset int, limit int) ([]User, error) {
+	var res []User
+	err := dao.db.WithContext(ctx).
+		Order("id").
+		Offset(offset).Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
 func (dao *GORMUserDAO) UpdateById(ctx context.Context, entity User) error {
 	return dao.db.WithContext(ctx).Model(&entity).Where("id=?", entity.Id).
 		Updates(map[string]any{
@@ -83,16 +115,65 @@ func (dao *GORMUserDAO) UpdateById(ctx context.Context, entity User) error {
 		}).Error
 }
 
+func (dao *GORMUserDAO) UpdatePassword(ctx context.Context, uid int64, password string) error {
+	return dao.db.WithContext(ctx).Model(&User{}).Where("id=?", uid).
+		Updates(map[string]any{
+			"updated_at": time.Now().UnixMilli(),
+			"password":   password,
+		}).Error
+}
+
+func (dao *GORMUserDAO) UpdateEmailVerified(ctx context.Context, uid int64, verified bool) error {
+	return dao.db.WithContext(ctx).Model(&User{}).Where("id=?", uid).
+		Updates(map[string]any{
+			"updated_at":     time.Now().UnixMilli(),
+			"email_verified": verified,
+		}).Error
+}
+
+func (dao *GORMUserDAO) UpdatePhone(ctx context.Context, uid int64, phone string) error {
+	err := dao.db.WithContext(ctx).Model(&User{}).Where("id=?", uid).
+		Updates(map[string]any{
+			"updated_at": time.Now().UnixMilli(),
+			"phone":      phone,
+		}).Error
+	if me, ok := err.(*mysql.MySQLError); ok {
+		const duplicateErr uint16 = 1062
+		if me.Number == duplicateErr {
+			return ErrDuplicatePhone
+		}
+	}
+	return err
+}
+
+func (dao *GORMUserDAO) UpdateWechat(ctx context.Context, uid int64, openId sql.NullString, unionId sql.NullString) error {
+	err := dao.db.WithContext(ctx).Model(&User{}).Where("id=?", uid).
+		Updates(map[string]any{
+			"updated_at":      time.Now().UnixMilli(),
+			"wechat_open_id":  openId,
+			"wechat_union_id": unionId,
+		}).Error
+	if me, ok := err.(*mysql.MySQLError); ok {
+		const duplicateErr uint16 = 1062
+		if me.Number == duplicateErr {
+			return ErrDuplicateWechat
+		}
+	}
+	return err
+}
+
 type User struct {
 	Id int64 `gorm:"primaryKey,autoIncrement"`
 	//Email    string `gorm:"unique"`
 	//Email is a nullable column
-	Email    sql.NullString `gorm:"unique"`
-	Password string
-	Nickname string         `gorm:"type=varchar(128)"`
-	Birthday int64          // YYYY-MM-DD
-	AboutMe  string         `gorm:"type=varchar(4096)"`
-	Phone    sql.NullString `gorm:"unique"`
+	Email sql.NullString `gorm:"unique"`
+	// EmailVerified is true once the user proves owning the email
+	EmailVerified bool
+	Password      string
+	Nickname      string         `gorm:"type=varchar(128)"`
+	Birthday      int64          // YYYY-MM-DD
+	AboutMe       string         `gorm:"type=varchar(4096)"`
+	Phone         sql.NullString `gorm:"unique"`
 
 	// 1 if the query requires both openid & unionid, need to create a composite unique key
 	// 2 if query requires openid only, then create unique key on openid, or <openid unionid> composite unique key
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository/dao"
+	"time"
+)
+
+type FeedRepository interface {
+	// CreatePushEvents pushes the event into the inboxes of the followers
+	CreatePushEvents(ctx context.Context, event domain.FeedEvent, followers []int64) error
+	CreatePullEvent(ctx context.Context, event domain.FeedEvent) error
+	FindPushEvents(ctx context.Context, uid int64, before time.Time, limit int) ([]domain.FeedEvent, error)
+	FindPullEvents(ctx context.Context, authorIds []int64, before time.Time, limit int) ([]domain.FeedEvent, error)
+	// DeletePushEvents empties uid's inbox of the author, it is called on unfollowing
+	DeletePushEvents(ctx context.Context, uid int64, authorId int64) error
+}
+
+// feedRepository has no cache, the inboxes are per user and read by the owner only
+type feedRepository struct {
+	dao dao.FeedDAO
+}
+
+func NewFeedRepository(dao dao.FeedDAO) FeedRepository {
+	return &feedRepository{
+		dao: dao,
+	}
+}
+
+func (repo *feedRepository) CreatePushEvents(ctx context.Context, event domain.FeedEvent, followers []int64) error {
+	events := make([]dao.FeedPushEvent, 0, len(followers))
+	for _, uid := range followers {
+		events = append(events, dao.FeedPushEvent{
+			Uid:       uid,
+			AuthorId:  event.AuthorId,
+			ArticleId: event.ArticleId,
+			Ctime:     event.Ctime.UnixMilli(),
+		})
+	}
+	return repo.dao.CreatePushEvents(ctx, events)
+}
+
+func (repo *feedRepository) CreatePullEvent(ctx context.Context, event domain.FeedEvent) error {
+	return repo.dao.CreatePullEvent(ctx, dao.FeedPullEvent{
+		AuthorId:  event.AuthorId,
+		ArticleId: event.ArticleId,
+		Ctime:     event.Ctime.UnixMilli(),
+	})
+}
+
+func (repo *feedRepository) FindPushEvents(ctx context.Context, uid int64, before time.Time, limit int) ([]domain.FeedEvent, error) {
+	events, err := repo.dao.FindPushEvents(ctx, uid, before.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.FeedEvent, 0, len(events))
+	for _, e := range events {
+		res = append(res, domain.FeedEvent{
+			ArticleId: e.ArticleId,
+			AuthorId:  e.AuthorId,
+			Ctime:     time.UnixMilli(e.Ctime),
+		})
+	}
+	return res, nil
+}
+
+func (repo *feedRepository) FindPullEvents(ctx context.Context, authorIds []int64, before time.Time, limit int) ([]domain.FeedEvent, error) {
+	events, err := repo.dao.FindPullEvents(ctx, authorIds, before.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.FeedEvent, 0, len(events))
+	for _, e := range events {
+		res = append(res, domain.FeedEvent{
+			ArticleId: e.ArticleId,
+			AuthorId:  e.AuthorId,
+			Ctime:     time.UnixMilli(e.Ctime),
+		})
+	}
+	return res, nil
+}
+
+func (repo *feedRepository) DeletePushEvents(ctx context.Context, uid int64, authorId int64) error {
+	return repo.dao.DeletePushEvents(ctx, uid, authorId)
+}
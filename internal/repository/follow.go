@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository/cache"
+	"github.com/webook/internal/repository/dao"
+	"log"
+	"time"
+)
+
+type FollowRepository interface {
+	AddFollowRelation(ctx context.Context, r domain.FollowRelation) error
+	DeleteFollowRelation(ctx context.Context, follower int64, followee int64) error
+	GetFollowees(ctx context.Context, follower int64, offset int, limit int) ([]domain.FollowRelation, error)
+	GetFollowers(ctx context.Context, followee int64, offset int, limit int) ([]domain.FollowRelation, error)
+	GetStatistics(ctx context.Context, uid int64) (domain.FollowStatistics, error)
+}
+
+type CachedFollowRepository struct {
+	dao   dao.FollowDAO
+	cache cache.FollowCache
+}
+
+func NewCachedFollowRepository(dao dao.FollowDAO, c cache.FollowCache) FollowRepository {
+	return &CachedFollowRepository{
+		dao:   dao,
+		cache: c,
+	}
+}
+
+func (repo *CachedFollowRepository) AddFollowRelation(ctx context.Context, r domain.FollowRelation) error {
+	changed, err := repo.dao.Insert(ctx, dao.FollowRelation{
+		Follower: r.Follower,
+		Followee: r.Followee,
+	})
+	if err != nil || !changed {
+		return err
+	}
+	repo.delStatistics(ctx, r.Follower, r.Followee)
+	return nil
+}
+
+func (repo *CachedFollowRepository) DeleteFollowRelation(ctx context.Context, follower int64, followee int64) error {
+	changed, err := repo.dao.Delete(ctx, follower, followee)
+	if err != nil || !changed {
+		return err
+	}
+	repo.delStatistics(ctx, follower, followee)
+	return nil
+}
+
+func (repo *CachedFollowRepository) GetFollowees(ctx context.Context, follower int64, offset int, limit int) ([]domain.FollowRelation, error) {
+	rs, err := repo.dao.FindFollowees(ctx, follower, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return repo.toDomains(rs), nil
+}
+
+func (repo *CachedFollowRepository) GetFollowers(ctx context.Context, followee int64, offset int, limit int) ([]domain.FollowRelation, error) {
+	rs, err := repo.dao.FindFollowers(ctx, followee, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return repo.toDomains(rs), nil
+}
+
+func (repo *CachedFollowRepository) GetStatistics(ctx context.Context, uid int64) (domain.FollowStatistics, error) {
+	res, err := repo.cache.GetStatistics(ctx, uid)
+	if err == nil {
+		return res, nil
+	}
+	res.Followers, err = repo.dao.CountFollowers(ctx, uid)
+	if err != nil {
+		return domain.FollowStatistics{}, err
+	}
+	res.Followees, err = repo.dao.CountFollowees(ctx, uid)
+	if err != nil {
+		return domain.FollowStatistics{}, err
+	}
+	if err = repo.cache.SetStatistics(ctx, uid, res); err != nil {
+		log.Println(err)
+	}
+	return res, nil
+}
+
+func (repo *CachedFollowRepository) delStatistics(ctx context.Context, uids ...int64) {
+	if err := repo.cache.DelStatistics(ctx, uids...); err != nil {
+		// the counts will be stale until they expire
+		log.Println(err)
+	}
+}
+
+func (repo *CachedFollowRepository) toDomains(rs []dao.FollowRelation) []domain.FollowRelation {
+	res := make([]domain.FollowRelation, 0, len(rs))
+	for _, r := range rs {
+		res = append(res, domain.FollowRelation{
+			Follower:  r.Follower,
+			Followee:  r.Followee,
+			CreatedAt: time.UnixMilli(r.CreatedAt),
+		})
+	}
+	return res
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository/cache"
+	"github.com/webook/internal/repository/dao"
+	"log"
+)
+
+type InteractiveRepository interface {
+	// IncrReadCnt counts a read of reader, which is a uid or a device fingerprint
+	IncrReadCnt(ctx context.Context, biz string, bizId int64, reader string) error
+	IncrLike(ctx context.Context, biz string, bizId int64, uid int64) error
+	DecrLike(ctx context.Context, biz string, bizId int64, uid int64) error
+	// AddCollectionItem collects it into uid's collection cid, or moves it there if collected already
+	AddCollectionItem(ctx context.Context, biz string, bizId int64, uid int64, cid int64) error
+	DeleteCollectionItem(ctx context.Context, biz string, bizId int64, uid int64) error
+	Get(ctx context.Context, biz string, bizId int64) (domain.Interactive, error)
+	Liked(ctx context.Context, biz string, bizId int64, uid int64) (bool, error)
+	Collected(ctx context.Context, biz string, bizId int64, uid int64) (bool, error)
+	// GetByIds goes to DB directly, it is used by batch jobs only
+	GetByIds(ctx context.Context, biz string, ids []int64) ([]domain.Interactive, error)
+}
+
+type CachedInteractiveRepository struct {
+	dao   dao.InteractiveDAO
+	cache cache.InteractiveCache
+	dedup cache.ReadDedupCache
+}
+
+func NewCachedInteractiveRepository(dao dao.InteractiveDAO, c cache.InteractiveCache,
+	dedup cache.ReadDedupCache) InteractiveRepository {
+	return &CachedInteractiveRepository{
+		dao:   dao,
+		cache: c,
+		dedup: dedup,
+	}
+}
+
+func (repo *CachedInteractiveRepository) IncrReadCnt(ctx context.Context, biz string, bizId int64, reader string) error {
+	unique, err := repo.dedup.Add(ctx, biz, bizId, reader)
+	if err != nil {
+		// take it as a repeated read, the unique count is a bit less then,
+		// which is better than inflated
+		log.Println(err)
+		unique = false
+	}
+	err = repo.dao.IncrReadCnt(ctx, biz, bizId, unique)
+	if err != nil {
+		return err
+	}
+	return repo.cache.IncrReadCntIfPresent(ctx, biz, bizId, unique)
+}
+
+func (repo *CachedInteractiveRepository) IncrLike(ctx context.Context, biz string, bizId int64, uid int64) error {
+	changed, err := repo.dao.InsertLikeInfo(ctx, biz, bizId, uid)
+	if err != nil || !changed {
+		return err
+	}
+	return repo.cache.IncrLikeCntIfPresent(ctx, biz, bizId)
+}
+
+func (repo *CachedInteractiveRepository) DecrLike(ctx context.Context, biz string, bizId int64, uid int64) error {
+	changed, err := repo.dao.DeleteLikeInfo(ctx, biz, bizId, uid)
+	if err != nil || !changed {
+		return err
+	}
+	return repo.cache.DecrLikeCntIfPresent(ctx, biz, bizId)
+}
+
+func (repo *CachedInteractiveRepository) AddCollectionItem(ctx context.Context, biz string, bizId int64, uid int64, cid int64) error {
+	changed, err := repo.dao.InsertCollectionBiz(ctx, biz, bizId, uid, cid)
+	if err != nil || !changed {
+		return err
+	}
+	return repo.cache.IncrCollectCntIfPresent(ctx, biz, bizId)
+}
+
+func (repo *CachedInteractiveRepository) DeleteCollectionItem(ctx context.Context, biz string, bizId int64, uid int64) error {
+	changed, err := repo.dao.DeleteCollectionBiz(ctx, biz, bizId, uid)
+	if err != nil || !changed {
+		return err
+	}
+	return repo.cache.DecrCollectCntIfPresent(ctx, biz, bizId)
+}
+
+func (repo *CachedInteractiveRepository) Get(ctx context.Context, biz string, bizId int64) (domain.Interactive, error) {
+	intr, err := repo.cache.Get(ctx, biz, bizId)
+	if err == nil {
+		return intr, nil
+	}
+	ie, err := repo.dao.Get(ctx, biz, bizId)
+	switch err {
+	case nil:
+		intr = repo.toDomain(ie)
+	case dao.ErrRecordNotFound:
+		// nobody has interacted with it yet
+		intr = domain.Interactive{Biz: biz, BizId: bizId}
+	default:
+		return domain.Interactive{}, err
+	}
+	if err = repo.cache.Set(ctx, intr); err != nil {
+		log.Println(err)
+	}
+	return intr, nil
+}
+
+func (repo *CachedInteractiveRepository) Liked(ctx context.Context, biz string, bizId int64, uid int64) (bool, error) {
+	_, err := repo.dao.GetLikeInfo(ctx, biz, bizId, uid)
+	switch err {
+	case nil:
+		return true, nil
+	case dao.ErrRecordNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (repo *CachedInteractiveRepository) Collected(ctx context.Context, biz string, bizId int64, uid int64) (bool, error) {
+	_, err := repo.dao.GetCollectionInfo(ctx, biz, bizId, uid)
+	switch err {
+	case nil:
+		return true, nil
+	case dao.ErrRecordNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (repo *CachedInteractiveRepository) GetByIds(ctx context.Context, biz string, ids []int64) ([]domain.Interactive, error) {
+	intrs, err := repo.dao.GetByIds(ctx, biz, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Interactive, 0, len(intrs))
+	for _, intr := range intrs {
+		res = append(res, repo.toDomain(intr))
+	}
+	return res, nil
+}
+
+func (repo *CachedInteractiveRepository) toDomain(ie dao.Interactive) domain.Interactive {
+	return domain.Interactive{
+		Biz:           ie.Biz,
+		BizId:         ie.BizId,
+		ReadCnt:       ie.ReadCnt,
+		UniqueReadCnt: ie.UniqueReadCnt,
+		LikeCnt:       ie.LikeCnt,
+		CollectCnt:    ie.CollectCnt,
+	}
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository/dao"
+	"time"
+)
+
+type NotificationRepository interface {
+	// Create collapses n into the unread one of the same kind created since the given time, if there is one
+	Create(ctx context.Context, n domain.Notification, since time.Time) error
+	FindByUid(ctx context.Context, uid int64, offset int, limit int) ([]domain.Notification, error)
+	CountUnread(ctx context.Context, uid int64) (int64, error)
+	MarkRead(ctx context.Context, uid int64, id int64) error
+	MarkAllRead(ctx context.Context, uid int64) error
+}
+
+type notificationRepository struct {
+	dao dao.NotificationDAO
+}
+
+func NewNotificationRepository(dao dao.NotificationDAO) NotificationRepository {
+	return &notificationRepository{
+		dao: dao,
+	}
+}
+
+func (repo *notificationRepository) Create(ctx context.Context, n domain.Notification, since time.Time) error {
+	return repo.dao.Upsert(ctx, dao.Notification{
+		Uid:     n.Uid,
+		Kind:    n.Kind,
+		Content: n.Content,
+	}, since.UnixMilli())
+}
+
+func (repo *notificationRepository) FindByUid(ctx context.Context, uid int64, offset int, limit int) ([]domain.Notification, error) {
+	ns, err := repo.dao.FindByUid(ctx, uid, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Notification, 0, len(ns))
+	for _, n := range ns {
+		res = append(res, domain.Notification{
+			Id:        n.Id,
+			Uid:       n.Uid,
+			Kind:      n.Kind,
+			Content:   n.Content,
+			Count:     n.Count,
+			Read:      n.Status == dao.NotificationStatusRead,
+			CreatedAt: time.UnixMilli(n.CreatedAt),
+			UpdatedAt: time.UnixMilli(n.UpdatedAt),
+		})
+	}
+	return res, nil
+}
+
+func (repo *notificationRepository) CountUnread(ctx context.Context, uid int64) (int64, error) {
+	return repo.dao.CountUnread(ctx, uid)
+}
+
+func (repo *notificationRepository) MarkRead(ctx context.Context, uid int64, id int64) error {
+	return repo.dao.MarkRead(ctx, uid, id)
+}
+
+func (repo *notificationRepository) MarkAllRead(ctx context.Context, uid int64) error {
+	return repo.dao.MarkAllRead(ctx, uid)
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository/cache"
+	"log"
+)
+
+type RankingRepository interface {
+	ReplaceTopN(ctx context.Context, arts []domain.HotArticle) error
+	GetTopN(ctx context.Context, n int) ([]domain.HotArticle, error)
+}
+
+// CachedRankingRepository has no DB, the hot list is computed by the ranking job.
+// Reads go to the local cache first, then Redis, and fall back to the local copy however old it is
+type CachedRankingRepository struct {
+	redis cache.RankingCache
+	local *cache.RankingLocalCache
+}
+
+func NewCachedRankingRepository(redis cache.RankingCache, local *cache.RankingLocalCache) RankingRepository {
+	return &CachedRankingRepository{
+		redis: redis,
+		local: local,
+	}
+}
+
+func (repo *CachedRankingRepository) ReplaceTopN(ctx context.Context, arts []domain.HotArticle) error {
+	repo.local.Set(arts)
+	return repo.redis.Replace(ctx, arts)
+}
+
+func (repo *CachedRankingRepository) GetTopN(ctx context.Context, n int) ([]domain.HotArticle, error) {
+	arts, err := repo.local.Get()
+	if err == nil {
+		return repo.truncate(arts, n), nil
+	}
+	arts, err = repo.redis.Get(ctx)
+	if err != nil {
+		// the list is stale, but better than nothing
+		stale, localErr := repo.local.ForceGet()
+		if localErr == nil {
+			log.Println(err)
+			return repo.truncate(stale, n), nil
+		}
+		if err == cache.ErrKeyNotExist {
+			// the ranking job hasn't finished its first run yet
+			return nil, nil
+		}
+		return nil, err
+	}
+	repo.local.Set(arts)
+	return repo.truncate(arts, n), nil
+}
+
+func (repo *CachedRankingRepository) truncate(arts []domain.HotArticle, n int) []domain.HotArticle {
+	if len(arts) > n {
+		return arts[:n]
+	}
+	return arts
+}
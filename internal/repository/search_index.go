@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository/cache"
+)
+
+type SearchIndexRepository interface {
+	// Publish broadcasts the change to all replicas, including this one
+	Publish(ctx context.Context, change domain.SearchIndexChange) error
+	Subscribe(ctx context.Context) <-chan domain.SearchIndexChange
+}
+
+type searchIndexRepository struct {
+	cache cache.SearchIndexCache
+}
+
+func NewSearchIndexRepository(cache cache.SearchIndexCache) SearchIndexRepository {
+	return &searchIndexRepository{
+		cache: cache,
+	}
+}
+
+func (repo *searchIndexRepository) Publish(ctx context.Context, change domain.SearchIndexChange) error {
+	return repo.cache.Publish(ctx, change)
+}
+
+func (repo *searchIndexRepository) Subscribe(ctx context.Context) <-chan domain.SearchIndexChange {
+	return repo.cache.Subscribe(ctx)
+}
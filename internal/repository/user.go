@@ -11,8 +11,10 @@ import (
 )
 
 var (
-	ErrDuplicateUser = dao.ErrDuplicateEmail
-	ErrUserNotfound  = dao.ErrRecordNotFound
+	ErrDuplicateUser   = dao.ErrDuplicateEmail
+	ErrDuplicatePhone  = dao.ErrDuplicatePhone
+	ErrDuplicateWechat = dao.ErrDuplicateWechat
+	ErrUserNotfound    = dao.ErrRecordNotFound
 )
 
 type UserRepository interface {
@@ -20,8 +22,19 @@ type UserRepository interface {
 	FindByEmail(ctx context.Context, email string) (domain.User, error)
 	UpdateNonZeroFields(ctx context.Context, user domain.User) error
 	FindById(ctx context.Context, uid int64) (domain.User, error)
+	// FindByIds goes to DB in one query, the users not found are skipped
+	FindByIds(ctx context.Context, uids []int64) ([]domain.User, error)
 	FindByPhone(ctx context.Context, phone string) (domain.User, error)
 	FindByWechat(ctx context.Context, openId string) (domain.User, error)
+	UpdatePassword(ctx context.Context, uid int64, password string) error
+	MarkEmailVerified(ctx context.Context, uid int64) error
+	// UpdatePhone returns ErrDuplicatePhone if another user has the phone
+	UpdatePhone(ctx context.Context, uid int64, phone string) error
+	// UpdateWechat returns ErrDuplicateWechat if another user has the openId,
+	// the empty WechatInfo unlinks Wechat
+	UpdateWechat(ctx context.Context, uid int64, info domain.WechatInfo) error
+	// List goes to DB directly, it is used by batch jobs only
+	List(ctx context.Context, offset int, limit int) ([]domain.User, error)
 }
 
 type CachedUserRepository struct {
@@ -70,6 +83,30 @@ func (repo *CachedUserRepository) FindByEmail(ctx context.Context, email string)
 	return repo.toDomain(u), nil
 }
 
+func (repo *CachedUserRepository) FindByIds(ctx context.Context, uids []int64) ([]domain.User, error) {
+	us, err := repo.dao.FindByIds(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.User, 0, len(us))
+	for _, u := range us {
+		res = append(res, repo.toDomain(u))
+	}
+	return res, nil
+}
+
+func (repo *CachedUserRepository) List(ctx context.Context, offset int, limit int) ([]domain.User, error) {
+	us, err := repo.dao.List(ctx, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.User, 0, len(us))
+	for _, u := range us {
+		res = append(res, repo.toDomain(u))
+	}
+	return res, nil
+}
+
 func (repo *CachedUserRepository) FindByPhone(ctx context.Context, phone string) (domain.User, error) {
 	u, err := repo.dao.FindByPhone(ctx, phone)
 	if err != nil {
@@ -157,16 +194,68 @@ func (repo *CachedUserRepository) UpdateNonZeroFields(ctx context.Context, user
 	return repo.cache.Del(ctx, user.Id)
 }
 
+func (repo *CachedUserRepository) UpdatePassword(ctx context.Context, uid int64, password string) error {
+	err := repo.dao.UpdatePassword(ctx, uid, password)
+	if err != nil {
+		return err
+	}
+	// the cached user carries the password hash, it expires soon anyway.
+	// Failing here makes the callers skip revoking the sessions after the password is changed
+	if err = repo.cache.Del(ctx, uid); err != nil {
+		log.Println(err)
+	}
+	return nil
+}
+
+func (repo *CachedUserRepository) MarkEmailVerified(ctx context.Context, uid int64) error {
+	err := repo.dao.UpdateEmailVerified(ctx, uid, true)
+	if err != nil {
+		return err
+	}
+	// the email is verified already, the cached user expires soon anyway
+	if err = repo.cache.Del(ctx, uid); err != nil {
+		log.Println(err)
+	}
+	return nil
+}
+
+func (repo *CachedUserRepository) UpdatePhone(ctx context.Context, uid int64, phone string) error {
+	err := repo.dao.UpdatePhone(ctx, uid, phone)
+	if err != nil {
+		return err
+	}
+	// the phone is bound already, the cached user expires soon anyway
+	if err = repo.cache.Del(ctx, uid); err != nil {
+		log.Println(err)
+	}
+	return nil
+}
+
+func (repo *CachedUserRepository) UpdateWechat(ctx context.Context, uid int64, info domain.WechatInfo) error {
+	err := repo.dao.UpdateWechat(ctx, uid,
+		sql.NullString{String: info.OpenId, Valid: info.OpenId != ""},
+		sql.NullString{String: info.UnionId, Valid: info.UnionId != ""})
+	if err != nil {
+		return err
+	}
+	// Wechat is linked or unlinked already, the cached user expires soon anyway
+	if err = repo.cache.Del(ctx, uid); err != nil {
+		log.Println(err)
+	}
+	return nil
+}
+
 func (repo *CachedUserRepository) toDomain(u dao.User) domain.User {
 	return domain.User{
-		Id:        u.Id,
-		Email:     u.Email.String,
-		Phone:     u.Phone.String,
-		Password:  u.Password,
-		AboutMe:   u.AboutMe,
-		Nickname:  u.Nickname,
-		Birthday:  time.UnixMilli(u.Birthday),
-		CreatedAt: time.UnixMilli(u.CreatedAt),
+		Id:            u.Id,
+		Email:         u.Email.String,
+		EmailVerified: u.EmailVerified,
+		Phone:         u.Phone.String,
+		Password:      u.Password,
+		AboutMe:       u.AboutMe,
+		Nickname:      u.Nickname,
+		Birthday:      time.UnixMilli(u.Birthday),
+		CreatedAt:     time.UnixMilli(u.CreatedAt),
 		WechatInfo: domain.WechatInfo{
 			OpenId:  u.WechatOpenId.String,
 			UnionId: u.WechatUnionId.String,
@@ -185,8 +274,9 @@ func (repo *CachedUserRepository) toEntity(u domain.User) dao.User {
 			String: u.Phone,
 			Valid:  u.Phone != "",
 		},
-		Password: u.Password,
-		Birthday: u.Birthday.UnixMilli(),
+		EmailVerified: u.EmailVerified,
+		Password:      u.Password,
+		Birthday:      u.Birthday.UnixMilli(),
 		WechatUnionId: sql.NullString{
 			String: u.WechatInfo.UnionId,
 			Valid:  u.WechatInfo.UnionId != "",
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository/cache"
+)
+
+type UserEventRepository interface {
+	// Append saves evt and broadcasts it, it returns evt with the id assigned
+	Append(ctx context.Context, evt domain.UserEvent) (domain.UserEvent, error)
+	// After returns the recent events of uid after id, the oldest first
+	After(ctx context.Context, uid int64, id string) ([]domain.UserEvent, error)
+	// Subscribe receives the events of all users published by all replicas
+	Subscribe(ctx context.Context) <-chan domain.UserEvent
+}
+
+type userEventRepository struct {
+	cache cache.UserEventCache
+}
+
+func NewUserEventRepository(cache cache.UserEventCache) UserEventRepository {
+	return &userEventRepository{
+		cache: cache,
+	}
+}
+
+func (repo *userEventRepository) Append(ctx context.Context, evt domain.UserEvent) (domain.UserEvent, error) {
+	return repo.cache.Append(ctx, evt)
+}
+
+func (repo *userEventRepository) After(ctx context.Context, uid int64, id string) ([]domain.UserEvent, error) {
+	return repo.cache.After(ctx, uid, id)
+}
+
+func (repo *userEventRepository) Subscribe(ctx context.Context) <-chan domain.UserEvent {
+	return repo.cache.Subscribe(ctx)
+}
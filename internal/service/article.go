@@ -0,0 +1,370 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+var (
+	ErrArticleNotFound  = repository.ErrArticleNotFound
+	ErrRevisionNotFound = repository.ErrRevisionNotFound
+	ErrInvalidPublishAt = errors.New("Publish time must be in the future")
+	ErrTooManyTags      = fmt.Errorf("An article can have at most %d tags", maxArticleTags)
+	ErrInvalidFormat    = errors.New("Unknown content format")
+)
+
+const (
+	maxArticleTags = 5
+	// maxTagLength is in runes, the same limit applies to category
+	maxTagLength = 32
+)
+
+type ArticleService interface {
+	Save(ctx context.Context, art domain.Article) (int64, error)
+	GetById(ctx context.Context, id int64) (domain.Article, error)
+	Publish(ctx context.Context, art domain.Article) (int64, error)
+	Withdraw(ctx context.Context, uid int64, id int64) error
+	List(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error)
+	GetPubById(ctx context.Context, id int64) (domain.Article, error)
+	ListRevisions(ctx context.Context, uid int64, aid int64) ([]domain.ArticleRevision, error)
+	// DiffRevisions returns the unified diff from revision from to revision to
+	DiffRevisions(ctx context.Context, uid int64, aid int64, from int64, to int64) (string, error)
+	// RestoreRevision saves the old revision as the current draft, which is a new revision itself
+	RestoreRevision(ctx context.Context, uid int64, aid int64, revision int64) error
+	// Schedule publishes the current draft at publishAt
+	Schedule(ctx context.Context, uid int64, id int64, publishAt time.Time) error
+	CancelSchedule(ctx context.Context, uid int64, id int64) error
+	// ListDueScheduled lists the scheduled articles which should be published by now
+	ListDueScheduled(ctx context.Context, now time.Time, limit int) ([]domain.Article, error)
+	// PublishScheduled publishes the scheduled article if it is still scheduled and due,
+	// returns false if it is not published for that reason
+	PublishScheduled(ctx context.Context, id int64) (bool, error)
+	// ListByTag lists the published articles with the tag, the most recently updated first
+	ListByTag(ctx context.Context, tag string, offset int, limit int) ([]domain.Article, error)
+	PopularTags(ctx context.Context, n int) ([]domain.Tag, error)
+	// ListLatestPub lists the latest published articles of the author with the author names,
+	// uid 0 lists those of all authors, it is for the syndication feeds
+	ListLatestPub(ctx context.Context, uid int64) ([]domain.Article, error)
+}
+
+type articleService struct {
+	repo      repository.ArticleRepository
+	revRepo   repository.ArticleRevisionRepository
+	userSvc   UserService
+	feedSvc   FeedService
+	searchSvc SearchService
+}
+
+func NewArticleService(repo repository.ArticleRepository,
+	revRepo repository.ArticleRevisionRepository,
+	userSvc UserService,
+	feedSvc FeedService,
+	searchSvc SearchService) ArticleService {
+	return &articleService{
+		repo:      repo,
+		revRepo:   revRepo,
+		userSvc:   userSvc,
+		feedSvc:   feedSvc,
+		searchSvc: searchSvc,
+	}
+}
+
+// Save creates a new draft when art.Id is 0, otherwise updates the existing draft
+func (svc *articleService) Save(ctx context.Context, art domain.Article) (int64, error) {
+	art, err := svc.normalize(art)
+	if err != nil {
+		return 0, err
+	}
+	art.Status = domain.ArticleStatusUnpublished
+	if art.Id > 0 {
+		// editing a scheduled draft keeps it scheduled
+		cur, getErr := svc.repo.GetById(ctx, art.Id)
+		if getErr == nil && cur.Status == domain.ArticleStatusScheduled {
+			art.Status = domain.ArticleStatusScheduled
+		}
+		err = svc.repo.Update(ctx, art)
+	} else {
+		art.Id, err = svc.repo.Create(ctx, art)
+	}
+	if err != nil {
+		return 0, err
+	}
+	svc.recordRevision(ctx, art)
+	return art.Id, nil
+}
+
+func (svc *articleService) GetById(ctx context.Context, id int64) (domain.Article, error) {
+	return svc.repo.GetById(ctx, id)
+}
+
+// Publish saves the draft and makes it visible to readers
+func (svc *articleService) Publish(ctx context.Context, art domain.Article) (int64, error) {
+	if err := svc.userSvc.CheckAllowed(ctx, art.Author.Id, UserActionPublish); err != nil {
+		return 0, err
+	}
+	return svc.publish(ctx, art)
+}
+
+func (svc *articleService) publish(ctx context.Context, art domain.Article) (int64, error) {
+	art, err := svc.normalize(art)
+	if err != nil {
+		return 0, err
+	}
+	art.Status = domain.ArticleStatusPublished
+	firstPublish := svc.isFirstPublish(ctx, art.Id)
+	id, err := svc.repo.Sync(ctx, art)
+	if err != nil {
+		return 0, err
+	}
+	art.Id = id
+	svc.afterPublish(ctx, art, firstPublish)
+	return id, nil
+}
+
+// afterPublish only logs the failures, the article is published anyway
+func (svc *articleService) afterPublish(ctx context.Context, art domain.Article, firstPublish bool) {
+	if err := svc.searchSvc.IndexArticle(ctx, art); err != nil {
+		// it is found once the index is rebuilt
+		zap.L().Error("Failed to index article", zap.Int64("aid", art.Id), zap.Error(err))
+	}
+	if firstPublish {
+		// the followers just miss it in their feeds
+		if err := svc.feedSvc.PushArticle(ctx, art); err != nil {
+			zap.L().Error("Failed to push article to feeds", zap.Int64("aid", art.Id), zap.Error(err))
+		}
+	}
+	svc.recordRevision(ctx, art)
+}
+
+// isFirstPublish tells whether the article has never been published,
+// republishing an edited or withdrawn article doesn't show up in feeds again
+func (svc *articleService) isFirstPublish(ctx context.Context, id int64) bool {
+	if id <= 0 {
+		return true
+	}
+	_, err := svc.repo.GetPubById(ctx, id)
+	return err == ErrArticleNotFound
+}
+
+// Withdraw hides the article from readers, it stays visible to the author
+func (svc *articleService) Withdraw(ctx context.Context, uid int64, id int64) error {
+	err := svc.repo.SyncStatus(ctx, uid, id, domain.ArticleStatusPrivate)
+	if err != nil {
+		return err
+	}
+	if err = svc.searchSvc.RemoveArticle(ctx, id); err != nil {
+		// the search results skip the withdrawn articles anyway
+		zap.L().Error("Failed to remove article from index", zap.Int64("aid", id), zap.Error(err))
+	}
+	return nil
+}
+
+// List returns the author's articles, the most recently updated first
+func (svc *articleService) List(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error) {
+	return svc.repo.GetByAuthor(ctx, uid, offset, limit)
+}
+
+// GetPubById returns the published version of the article with its author's name,
+// withdrawn (private) articles are treated as not found
+func (svc *articleService) GetPubById(ctx context.Context, id int64) (domain.Article, error) {
+	art, err := svc.repo.GetPubById(ctx, id)
+	if err != nil {
+		return domain.Article{}, err
+	}
+	if art.Status != domain.ArticleStatusPublished {
+		return domain.Article{}, ErrArticleNotFound
+	}
+	author, err := svc.userSvc.FindById(ctx, art.Author.Id)
+	if err != nil {
+		return domain.Article{}, err
+	}
+	art.Author.Name = author.Nickname
+	return art, nil
+}
+
+func (svc *articleService) ListLatestPub(ctx context.Context, uid int64) ([]domain.Article, error) {
+	arts, err := svc.repo.LatestPub(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	uids := make([]int64, 0, len(arts))
+	for _, art := range arts {
+		uids = append(uids, art.Author.Id)
+	}
+	// the names of the authors not found are left empty
+	authors, err := svc.userSvc.FindByIds(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range arts {
+		arts[i].Author.Name = authors[arts[i].Author.Id].Nickname
+	}
+	return arts, nil
+}
+
+func (svc *articleService) ListRevisions(ctx context.Context, uid int64, aid int64) ([]domain.ArticleRevision, error) {
+	if err := svc.checkAuthor(ctx, uid, aid); err != nil {
+		return nil, err
+	}
+	return svc.revRepo.GetByArticle(ctx, aid)
+}
+
+func (svc *articleService) DiffRevisions(ctx context.Context, uid int64, aid int64, from int64, to int64) (string, error) {
+	if err := svc.checkAuthor(ctx, uid, aid); err != nil {
+		return "", err
+	}
+	fromRev, err := svc.revRepo.GetByRevision(ctx, aid, from)
+	if err != nil {
+		return "", err
+	}
+	toRev, err := svc.revRepo.GetByRevision(ctx, aid, to)
+	if err != nil {
+		return "", err
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(svc.revisionText(fromRev)),
+		B:        difflib.SplitLines(svc.revisionText(toRev)),
+		FromFile: fmt.Sprintf("revision %d", from),
+		ToFile:   fmt.Sprintf("revision %d", to),
+		Context:  3,
+	})
+}
+
+func (svc *articleService) RestoreRevision(ctx context.Context, uid int64, aid int64, revision int64) error {
+	art, err := svc.getOwnArticle(ctx, uid, aid)
+	if err != nil {
+		return err
+	}
+	rev, err := svc.revRepo.GetByRevision(ctx, aid, revision)
+	if err != nil {
+		return err
+	}
+	// revisions only track title and content, the others are kept as they are now
+	art.Title = rev.Title
+	art.Content = rev.Content
+	_, err = svc.Save(ctx, art)
+	return err
+}
+
+// recordRevision only logs the failure, the article is saved already,
+// failing the request makes the client save a new draft again
+func (svc *articleService) recordRevision(ctx context.Context, art domain.Article) {
+	_, err := svc.revRepo.Create(ctx, domain.ArticleRevision{
+		ArticleId: art.Id,
+		Title:     art.Title,
+		Content:   art.Content,
+	})
+	if err != nil {
+		zap.L().Error("Failed to record revision", zap.Int64("aid", art.Id), zap.Error(err))
+	}
+}
+
+// checkAuthor makes sure only the author can see and restore the revisions
+func (svc *articleService) checkAuthor(ctx context.Context, uid int64, aid int64) error {
+	_, err := svc.getOwnArticle(ctx, uid, aid)
+	return err
+}
+
+func (svc *articleService) getOwnArticle(ctx context.Context, uid int64, aid int64) (domain.Article, error) {
+	art, err := svc.repo.GetById(ctx, aid)
+	if err != nil {
+		return domain.Article{}, err
+	}
+	if art.Author.Id != uid {
+		return domain.Article{}, ErrArticleNotFound
+	}
+	return art, nil
+}
+
+// revisionText puts the title on the first line, so that title changes show up in the diff
+func (svc *articleService) revisionText(rev domain.ArticleRevision) string {
+	return rev.Title + "\n\n" + rev.Content
+}
+
+func (svc *articleService) Schedule(ctx context.Context, uid int64, id int64, publishAt time.Time) error {
+	if !publishAt.After(time.Now()) {
+		return ErrInvalidPublishAt
+	}
+	if err := svc.userSvc.CheckAllowed(ctx, uid, UserActionPublish); err != nil {
+		return err
+	}
+	return svc.repo.Schedule(ctx, uid, id, publishAt)
+}
+
+func (svc *articleService) CancelSchedule(ctx context.Context, uid int64, id int64) error {
+	return svc.repo.CancelSchedule(ctx, uid, id)
+}
+
+func (svc *articleService) ListDueScheduled(ctx context.Context, now time.Time, limit int) ([]domain.Article, error) {
+	return svc.repo.FindScheduled(ctx, now, limit)
+}
+
+func (svc *articleService) PublishScheduled(ctx context.Context, id int64) (bool, error) {
+	firstPublish := svc.isFirstPublish(ctx, id)
+	// checked against DB under the row lock, it may be cancelled or published by others since it was listed.
+	// The draft was normalized and the author checked when it was scheduled
+	art, err := svc.repo.PublishScheduled(ctx, id)
+	if err == repository.ErrArticleNotScheduled {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	svc.afterPublish(ctx, art, firstPublish)
+	return true, nil
+}
+
+func (svc *articleService) ListByTag(ctx context.Context, tag string, offset int, limit int) ([]domain.Article, error) {
+	return svc.repo.GetPubByTag(ctx, svc.normalizeTag(tag), offset, limit)
+}
+
+func (svc *articleService) PopularTags(ctx context.Context, n int) ([]domain.Tag, error) {
+	return svc.repo.PopularTags(ctx, n)
+}
+
+// normalize validates the format, normalizes the tags and category, and removes the duplicated tags
+func (svc *articleService) normalize(art domain.Article) (domain.Article, error) {
+	if !art.Format.Valid() {
+		return art, ErrInvalidFormat
+	}
+	tags := make([]string, 0, len(art.Tags))
+	seen := make(map[string]struct{}, len(art.Tags))
+	for _, tag := range art.Tags {
+		tag = svc.normalizeTag(tag)
+		if tag == "" {
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxArticleTags {
+		return art, ErrTooManyTags
+	}
+	art.Tags = tags
+	art.Category = svc.normalizeTag(art.Category)
+	return art, nil
+}
+
+// normalizeTag turns " #Go  Lang " into "go-lang"
+func (svc *articleService) normalizeTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	tag = strings.TrimLeft(tag, "#")
+	// comma is the separator when tags are stored
+	tag = strings.ReplaceAll(tag, ",", " ")
+	tag = strings.Join(strings.Fields(tag), "-")
+	runes := []rune(tag)
+	if len(runes) > maxTagLength {
+		runes = runes[:maxTagLength]
+	}
+	return strings.TrimRight(string(runes), "-")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/webook/internal/repository"
+	"github.com/webook/internal/service/email"
 	"github.com/webook/internal/service/sms"
 	"math/rand"
 )
@@ -46,6 +47,49 @@ func (svc *codeService) Verify(ctx context.Context, biz, phone, inputCode string
 }
 
 func (svc *codeService) generate() string {
+	return generateCode()
+}
+
+// EmailCodeService sends the codes by email, with the same limits as the SMS codes
+type EmailCodeService interface {
+	Send(ctx context.Context, biz, addr string) error
+	Verify(ctx context.Context, biz, addr, inputCode string) (bool, error)
+}
+
+type emailCodeService struct {
+	repo  repository.CodeRepository
+	email email.Service
+}
+
+func NewEmailCodeService(repo repository.CodeRepository, emailSvc email.Service) EmailCodeService {
+	return &emailCodeService{
+		repo:  repo,
+		email: emailSvc,
+	}
+}
+
+func (svc *emailCodeService) Send(ctx context.Context, biz, addr string) error {
+	code := generateCode()
+	// the email address takes the place of the phone in the cache
+	err := svc.repo.Set(ctx, biz, addr, code)
+	if err != nil {
+		return err
+	}
+	return svc.email.Send(ctx, "Your verification code",
+		fmt.Sprintf("Your verification code is %s, it expires in 10 minutes.\n"+
+			"If you didn't ask for it, please ignore this email.", code), addr)
+}
+
+func (svc *emailCodeService) Verify(ctx context.Context, biz, addr, inputCode string) (bool, error) {
+	ok, err := svc.repo.Verify(ctx, biz, addr, inputCode)
+	if err == repository.ErrCodeVerifyTooMany {
+		// same as the SMS codes, do not expose it
+		return false, nil
+	}
+	return ok, err
+}
+
+func generateCode() string {
 	// 0-999999
 	code := rand.Intn(1000000)
 	return fmt.Sprintf("%06d", code)
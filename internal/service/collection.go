@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxCollectionNameLength = 64
+
+var (
+	ErrCollectionNotFound      = repository.ErrCollectionNotFound
+	ErrDuplicateCollectionName = repository.ErrDuplicateCollectionName
+	ErrInvalidCollectionName   = fmt.Errorf("Collection name must be 1 to %d characters", maxCollectionNameLength)
+)
+
+type CollectionService interface {
+	Create(ctx context.Context, c domain.Collection) (int64, error)
+	// Update renames the collection or changes its visibility, only the owner can do it
+	Update(ctx context.Context, c domain.Collection) error
+	// Delete deletes the collection, the articles in it go into the default collection
+	Delete(ctx context.Context, uid int64, id int64) error
+	// List lists uid's collections, the viewer only sees the public ones unless it is uid
+	List(ctx context.Context, uid int64, viewer int64, offset int, limit int) ([]domain.Collection, error)
+	// Articles lists the published articles in the collection, the latest collected first.
+	// The private collections are taken as not found unless the viewer is the owner,
+	// cid 0 is the default collection of the viewer
+	Articles(ctx context.Context, cid int64, viewer int64, offset int, limit int) ([]domain.Article, error)
+}
+
+type collectionService struct {
+	repo    repository.CollectionRepository
+	artRepo repository.ArticleRepository
+}
+
+func NewCollectionService(repo repository.CollectionRepository, artRepo repository.ArticleRepository) CollectionService {
+	return &collectionService{
+		repo:    repo,
+		artRepo: artRepo,
+	}
+}
+
+func (svc *collectionService) Create(ctx context.Context, c domain.Collection) (int64, error) {
+	var ok bool
+	if c.Name, ok = svc.checkName(c.Name); !ok {
+		return 0, ErrInvalidCollectionName
+	}
+	return svc.repo.Create(ctx, c)
+}
+
+func (svc *collectionService) Update(ctx context.Context, c domain.Collection) error {
+	var ok bool
+	if c.Name, ok = svc.checkName(c.Name); !ok {
+		return ErrInvalidCollectionName
+	}
+	return svc.repo.Update(ctx, c)
+}
+
+func (svc *collectionService) Delete(ctx context.Context, uid int64, id int64) error {
+	return svc.repo.Delete(ctx, uid, id)
+}
+
+func (svc *collectionService) List(ctx context.Context, uid int64, viewer int64, offset int, limit int) ([]domain.Collection, error) {
+	return svc.repo.FindByUid(ctx, uid, uid != viewer, offset, limit)
+}
+
+func (svc *collectionService) Articles(ctx context.Context, cid int64, viewer int64, offset int, limit int) ([]domain.Article, error) {
+	owner := viewer
+	if cid != 0 {
+		c, err := svc.repo.FindById(ctx, cid)
+		if err != nil {
+			return nil, err
+		}
+		if !c.Public && c.Uid != viewer {
+			return nil, ErrCollectionNotFound
+		}
+		owner = c.Uid
+	}
+	ids, err := svc.repo.FindItems(ctx, owner, cid, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	arts := make([]domain.Article, 0, len(ids))
+	for _, id := range ids {
+		art, err := svc.artRepo.GetPubById(ctx, id)
+		if err == ErrArticleNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if art.Status != domain.ArticleStatusPublished {
+			// withdrawn after it was collected
+			continue
+		}
+		arts = append(arts, art)
+	}
+	return arts, nil
+}
+
+// checkName returns the trimmed name, and whether it is valid
+func (svc *collectionService) checkName(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	cnt := utf8.RuneCountInString(name)
+	return name, cnt > 0 && cnt <= maxCollectionNameLength
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrCommentNotFound         = repository.ErrCommentNotFound
+	ErrInvalidComment          = fmt.Errorf("Comment must be 1 to %d characters", maxCommentLength)
+	ErrCommentPermissionDenied = errors.New("Only the commenter or the article author can delete the comment")
+)
+
+const (
+	// maxCommentLength is in runes
+	maxCommentLength = 1024
+	// firstReplies is how many replies are shown with each top-level comment
+	firstReplies = 3
+)
+
+type CommentService interface {
+	// Create comments on a published article, or replies to c.ParentId if it is not 0
+	Create(ctx context.Context, c domain.Comment) (int64, error)
+	// Delete deletes the comment and the replies to it, only the commenter and the article author can delete it
+	Delete(ctx context.Context, uid int64, id int64) error
+	// List lists the top-level comments, the newest first, each with its first few replies.
+	// It returns ErrArticleNotFound if the article is not published, so does ListReplies
+	List(ctx context.Context, aid int64, offset int, limit int) ([]domain.Comment, error)
+	// ListReplies lists the replies in the thread, the oldest first
+	ListReplies(ctx context.Context, rootId int64, offset int, limit int) ([]domain.Comment, error)
+	Count(ctx context.Context, aid int64) (int64, error)
+}
+
+type commentService struct {
+	repo    repository.CommentRepository
+	artRepo repository.ArticleRepository
+	userSvc UserService
+}
+
+func NewCommentService(repo repository.CommentRepository,
+	artRepo repository.ArticleRepository,
+	userSvc UserService) CommentService {
+	return &commentService{
+		repo:    repo,
+		artRepo: artRepo,
+		userSvc: userSvc,
+	}
+}
+
+func (svc *commentService) Create(ctx context.Context, c domain.Comment) (int64, error) {
+	c.Content = strings.TrimSpace(c.Content)
+	if c.Content == "" || utf8.RuneCountInString(c.Content) > maxCommentLength {
+		return 0, ErrInvalidComment
+	}
+	if err := svc.userSvc.CheckAllowed(ctx, c.Commentator.Id, UserActionComment); err != nil {
+		return 0, err
+	}
+	if err := svc.checkPublished(ctx, c.ArticleId); err != nil {
+		return 0, err
+	}
+	c.RootId = 0
+	if c.ParentId > 0 {
+		parent, err := svc.repo.GetById(ctx, c.ParentId)
+		if err != nil {
+			return 0, err
+		}
+		if parent.ArticleId != c.ArticleId {
+			return 0, ErrCommentNotFound
+		}
+		// replies are flattened into the thread of the top-level comment
+		c.RootId = parent.RootId
+		if c.RootId == 0 {
+			c.RootId = parent.Id
+		}
+	}
+	return svc.repo.Create(ctx, c)
+}
+
+func (svc *commentService) Delete(ctx context.Context, uid int64, id int64) error {
+	c, err := svc.repo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if c.Commentator.Id != uid {
+		// the draft always exists, even if the article is withdrawn
+		art, err := svc.artRepo.GetById(ctx, c.ArticleId)
+		if err != nil {
+			return err
+		}
+		if art.Author.Id != uid {
+			return ErrCommentPermissionDenied
+		}
+	}
+	return svc.repo.Delete(ctx, c)
+}
+
+func (svc *commentService) List(ctx context.Context, aid int64, offset int, limit int) ([]domain.Comment, error) {
+	if err := svc.checkPublished(ctx, aid); err != nil {
+		return nil, err
+	}
+	cs, err := svc.repo.FindRoots(ctx, aid, offset, limit, firstReplies)
+	if err != nil {
+		return nil, err
+	}
+	return cs, svc.fillCommentators(ctx, cs)
+}
+
+func (svc *commentService) ListReplies(ctx context.Context, rootId int64, offset int, limit int) ([]domain.Comment, error) {
+	root, err := svc.repo.GetById(ctx, rootId)
+	if err != nil {
+		return nil, err
+	}
+	if err = svc.checkPublished(ctx, root.ArticleId); err != nil {
+		return nil, err
+	}
+	cs, err := svc.repo.FindReplies(ctx, rootId, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return cs, svc.fillCommentators(ctx, cs)
+}
+
+// checkPublished returns ErrArticleNotFound if the readers can't see the article,
+// its comments are hidden as well
+func (svc *commentService) checkPublished(ctx context.Context, aid int64) error {
+	art, err := svc.artRepo.GetPubById(ctx, aid)
+	if err != nil {
+		return err
+	}
+	if art.Status != domain.ArticleStatusPublished {
+		return ErrArticleNotFound
+	}
+	return nil
+}
+
+func (svc *commentService) Count(ctx context.Context, aid int64) (int64, error) {
+	return svc.repo.Count(ctx, aid)
+}
+
+// fillCommentators loads the commentators' names of the comments and their replies in one query,
+// the names of the users not found are left empty
+func (svc *commentService) fillCommentators(ctx context.Context, cs []domain.Comment) error {
+	uids := make([]int64, 0, len(cs))
+	for _, c := range cs {
+		uids = append(uids, c.Commentator.Id)
+		for _, r := range c.Replies {
+			uids = append(uids, r.Commentator.Id)
+		}
+	}
+	users, err := svc.userSvc.FindByIds(ctx, uids)
+	if err != nil {
+		return err
+	}
+	for i := range cs {
+		cs[i].Commentator.Name = users[cs[i].Commentator.Id].Nickname
+		for j := range cs[i].Replies {
+			cs[i].Replies[j].Commentator.Name = users[cs[i].Replies[j].Commentator.Id].Nickname
+		}
+	}
+	return nil
+}
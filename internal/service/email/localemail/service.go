@@ -0,0 +1,19 @@
+package localemail
+
+import (
+	"context"
+	"log"
+)
+
+// Service only logs the emails, it is for local development
+type Service struct {
+}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+func (s *Service) Send(ctx context.Context, subject string, body string, to ...string) error {
+	log.Println("Email to", to, subject, body)
+	return nil
+}
@@ -0,0 +1,43 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+)
+
+// Service sends the emails through an SMTP server with PLAIN auth
+type Service struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewService(host string, port int, username string, password string, from string) *Service {
+	return &Service{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		auth: smtp.PlainAuth("", username, password, host),
+		from: from,
+	}
+}
+
+func (s *Service) Send(ctx context.Context, subject string, body string, to ...string) error {
+	// net/smtp knows nothing about ctx, at least do not start when it is done
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return smtp.SendMail(s.addr, s.auth, s.from, to, msg.Bytes())
+}
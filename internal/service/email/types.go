@@ -0,0 +1,8 @@
+package email
+
+import "context"
+
+// Service is the abstract to send emails, the body is plain text
+type Service interface {
+	Send(ctx context.Context, subject string, body string, to ...string) error
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository"
+	"sort"
+	"time"
+)
+
+type FeedService interface {
+	// PushArticle delivers a newly published article to the followers of its author.
+	// Authors with no more than pushThreshold followers push it into the inboxes of the followers,
+	// the others leave it to the followers to pull when reading their feeds
+	PushArticle(ctx context.Context, art domain.Article) error
+	// Feed returns the articles first published before cursor by the authors uid follows, the newest first,
+	// with the cursor of the next page, which is zero if there are no more
+	Feed(ctx context.Context, uid int64, cursor time.Time, limit int) ([]domain.Article, time.Time, error)
+}
+
+type feedService struct {
+	repo       repository.FeedRepository
+	followRepo repository.FollowRepository
+	artRepo    repository.ArticleRepository
+
+	pushThreshold int64
+	batchSize     int
+	// maxFollowees is how many followees are pulled from, the latest followed ones
+	maxFollowees int
+}
+
+func NewFeedService(repo repository.FeedRepository,
+	followRepo repository.FollowRepository,
+	artRepo repository.ArticleRepository,
+	pushThreshold int64) FeedService {
+	return &feedService{
+		repo:          repo,
+		followRepo:    followRepo,
+		artRepo:       artRepo,
+		pushThreshold: pushThreshold,
+		batchSize:     500,
+		maxFollowees:  1000,
+	}
+}
+
+func (svc *feedService) PushArticle(ctx context.Context, art domain.Article) error {
+	event := domain.FeedEvent{
+		ArticleId: art.Id,
+		AuthorId:  art.Author.Id,
+		Ctime:     time.Now(),
+	}
+	statistics, err := svc.followRepo.GetStatistics(ctx, art.Author.Id)
+	if err != nil {
+		return err
+	}
+	if statistics.Followers > svc.pushThreshold {
+		return svc.repo.CreatePullEvent(ctx, event)
+	}
+	for offset := 0; ; offset += svc.batchSize {
+		rs, err := svc.followRepo.GetFollowers(ctx, art.Author.Id, offset, svc.batchSize)
+		if err != nil {
+			return err
+		}
+		followers := make([]int64, 0, len(rs))
+		for _, r := range rs {
+			followers = append(followers, r.Follower)
+		}
+		if err = svc.repo.CreatePushEvents(ctx, event, followers); err != nil {
+			return err
+		}
+		if len(rs) < svc.batchSize {
+			return nil
+		}
+	}
+}
+
+func (svc *feedService) Feed(ctx context.Context, uid int64, cursor time.Time, limit int) ([]domain.Article, time.Time, error) {
+	rs, err := svc.followRepo.GetFollowees(ctx, uid, 0, svc.maxFollowees)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	authorIds := make([]int64, 0, len(rs))
+	for _, r := range rs {
+		authorIds = append(authorIds, r.Followee)
+	}
+	// each side has at most limit events before cursor, so the merged top limit is exact
+	pulls, err := svc.repo.FindPullEvents(ctx, authorIds, cursor, limit)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	pushes, err := svc.repo.FindPushEvents(ctx, uid, cursor, limit)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	events := append(pulls, pushes...)
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Ctime.After(events[j].Ctime)
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	if len(events) == 0 {
+		return nil, time.Time{}, nil
+	}
+	var next time.Time
+	if len(events) == limit {
+		// events published in the same millisecond as the last one may be skipped by the next page,
+		// it is rare enough for a feed
+		next = events[len(events)-1].Ctime
+	}
+	arts := make([]domain.Article, 0, len(events))
+	for _, e := range events {
+		art, err := svc.artRepo.GetPubById(ctx, e.ArticleId)
+		if err == ErrArticleNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		if art.Status != domain.ArticleStatusPublished {
+			// withdrawn after it was pushed
+			continue
+		}
+		arts = append(arts, art)
+	}
+	return arts, next, nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository"
+)
+
+var ErrFollowSelf = errors.New("Users can't follow themselves")
+
+type FollowService interface {
+	// Follow is idempotent, following twice is the same as once
+	Follow(ctx context.Context, follower int64, followee int64) error
+	CancelFollow(ctx context.Context, follower int64, followee int64) error
+	// Followees lists the users uid follows, the latest followed first
+	Followees(ctx context.Context, uid int64, offset int, limit int) ([]domain.User, error)
+	// Followers lists the users following uid, the latest followed first
+	Followers(ctx context.Context, uid int64, offset int, limit int) ([]domain.User, error)
+	Statistics(ctx context.Context, uid int64) (domain.FollowStatistics, error)
+}
+
+type followService struct {
+	repo     repository.FollowRepository
+	feedRepo repository.FeedRepository
+	userSvc  UserService
+}
+
+func NewFollowService(repo repository.FollowRepository, feedRepo repository.FeedRepository,
+	userSvc UserService) FollowService {
+	return &followService{
+		repo:     repo,
+		feedRepo: feedRepo,
+		userSvc:  userSvc,
+	}
+}
+
+func (svc *followService) Follow(ctx context.Context, follower int64, followee int64) error {
+	if follower == followee {
+		return ErrFollowSelf
+	}
+	// make sure the followee exists, FindById returns ErrUserNotFound otherwise
+	if _, err := svc.userSvc.FindById(ctx, followee); err != nil {
+		return err
+	}
+	return svc.repo.AddFollowRelation(ctx, domain.FollowRelation{
+		Follower: follower,
+		Followee: followee,
+	})
+}
+
+func (svc *followService) CancelFollow(ctx context.Context, follower int64, followee int64) error {
+	err := svc.repo.DeleteFollowRelation(ctx, follower, followee)
+	if err != nil {
+		return err
+	}
+	// the articles pushed before stay in the inbox otherwise,
+	// unfollowing again cleans them up if it fails
+	return svc.feedRepo.DeletePushEvents(ctx, follower, followee)
+}
+
+func (svc *followService) Followees(ctx context.Context, uid int64, offset int, limit int) ([]domain.User, error) {
+	rs, err := svc.repo.GetFollowees(ctx, uid, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	uids := make([]int64, 0, len(rs))
+	for _, r := range rs {
+		uids = append(uids, r.Followee)
+	}
+	return svc.findUsers(ctx, uids)
+}
+
+func (svc *followService) Followers(ctx context.Context, uid int64, offset int, limit int) ([]domain.User, error) {
+	rs, err := svc.repo.GetFollowers(ctx, uid, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	uids := make([]int64, 0, len(rs))
+	for _, r := range rs {
+		uids = append(uids, r.Follower)
+	}
+	return svc.findUsers(ctx, uids)
+}
+
+func (svc *followService) Statistics(ctx context.Context, uid int64) (domain.FollowStatistics, error) {
+	return svc.repo.GetStatistics(ctx, uid)
+}
+
+// findUsers loads the users in one query keeping the order of uids, the users not found are skipped
+func (svc *followService) findUsers(ctx context.Context, uids []int64) ([]domain.User, error) {
+	users, err := svc.userSvc.FindByIds(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.User, 0, len(uids))
+	for _, uid := range uids {
+		if u, ok := users[uid]; ok {
+			res = append(res, u)
+		}
+	}
+	return res, nil
+}
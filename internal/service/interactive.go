@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository"
+)
+
+type InteractiveService interface {
+	// IncrReadCnt counts a read, reader is who reads it, a uid or a device fingerprint,
+	// the unique read count only counts the same reader once within the dedup window
+	IncrReadCnt(ctx context.Context, biz string, bizId int64, reader string) error
+	// Like and CancelLike are idempotent, liking twice only counts once
+	Like(ctx context.Context, biz string, bizId int64, uid int64) error
+	CancelLike(ctx context.Context, biz string, bizId int64, uid int64) error
+	// Collect and CancelCollect are idempotent as well, collecting again moves it into the collection cid,
+	// cid 0 is the default collection
+	Collect(ctx context.Context, biz string, bizId int64, uid int64, cid int64) error
+	CancelCollect(ctx context.Context, biz string, bizId int64, uid int64) error
+	// Get returns the counters, and whether uid liked or collected it, uid 0 is an anonymous reader
+	Get(ctx context.Context, biz string, bizId int64, uid int64) (domain.Interactive, error)
+}
+
+type interactiveService struct {
+	repo           repository.InteractiveRepository
+	collectionRepo repository.CollectionRepository
+}
+
+func NewInteractiveService(repo repository.InteractiveRepository,
+	collectionRepo repository.CollectionRepository) InteractiveService {
+	return &interactiveService{
+		repo:           repo,
+		collectionRepo: collectionRepo,
+	}
+}
+
+func (svc *interactiveService) IncrReadCnt(ctx context.Context, biz string, bizId int64, reader string) error {
+	return svc.repo.IncrReadCnt(ctx, biz, bizId, reader)
+}
+
+func (svc *interactiveService) Like(ctx context.Context, biz string, bizId int64, uid int64) error {
+	return svc.repo.IncrLike(ctx, biz, bizId, uid)
+}
+
+func (svc *interactiveService) CancelLike(ctx context.Context, biz string, bizId int64, uid int64) error {
+	return svc.repo.DecrLike(ctx, biz, bizId, uid)
+}
+
+func (svc *interactiveService) Collect(ctx context.Context, biz string, bizId int64, uid int64, cid int64) error {
+	if cid != 0 {
+		c, err := svc.collectionRepo.FindById(ctx, cid)
+		if err != nil {
+			return err
+		}
+		if c.Uid != uid {
+			// not telling whether others' collections exist
+			return ErrCollectionNotFound
+		}
+	}
+	return svc.repo.AddCollectionItem(ctx, biz, bizId, uid, cid)
+}
+
+func (svc *interactiveService) CancelCollect(ctx context.Context, biz string, bizId int64, uid int64) error {
+	return svc.repo.DeleteCollectionItem(ctx, biz, bizId, uid)
+}
+
+func (svc *interactiveService) Get(ctx context.Context, biz string, bizId int64, uid int64) (domain.Interactive, error) {
+	intr, err := svc.repo.Get(ctx, biz, bizId)
+	if err != nil {
+		return domain.Interactive{}, err
+	}
+	if uid <= 0 {
+		return intr, nil
+	}
+	intr.Liked, err = svc.repo.Liked(ctx, biz, bizId, uid)
+	if err != nil {
+		return domain.Interactive{}, err
+	}
+	intr.Collected, err = svc.repo.Collected(ctx, biz, bizId, uid)
+	if err != nil {
+		return domain.Interactive{}, err
+	}
+	return intr, nil
+}
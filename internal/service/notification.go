@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository"
+	"time"
+)
+
+type NotificationService interface {
+	// Record saves the event into the notification center of evt.Uid,
+	// it is subscribed to domain.TopicNotification, the producers publish the events to the bus
+	Record(ctx context.Context, evt domain.NotificationEvent) error
+	// List lists uid's notifications, the latest first
+	List(ctx context.Context, uid int64, offset int, limit int) ([]domain.Notification, error)
+	UnreadCount(ctx context.Context, uid int64) (int64, error)
+	MarkRead(ctx context.Context, uid int64, id int64) error
+	MarkAllRead(ctx context.Context, uid int64) error
+	// HandleEvent adapts Record to the event bus
+	HandleEvent(ctx context.Context, payload any) error
+}
+
+type notificationService struct {
+	repo repository.NotificationRepository
+	// collapseWindow is how far back repeated events are collapsed into one notification
+	collapseWindow time.Duration
+}
+
+func NewNotificationService(repo repository.NotificationRepository) NotificationService {
+	return &notificationService{
+		repo:           repo,
+		collapseWindow: time.Hour * 24 * 7,
+	}
+}
+
+func (svc *notificationService) Record(ctx context.Context, evt domain.NotificationEvent) error {
+	if evt.Time.IsZero() {
+		evt.Time = time.Now()
+	}
+	return svc.repo.Create(ctx, domain.Notification{
+		Uid:     evt.Uid,
+		Kind:    evt.Kind,
+		Content: evt.Content,
+	}, evt.Time.Add(-svc.collapseWindow))
+}
+
+func (svc *notificationService) HandleEvent(ctx context.Context, payload any) error {
+	evt, ok := payload.(domain.NotificationEvent)
+	if !ok {
+		return fmt.Errorf("unexpected notification payload %T", payload)
+	}
+	return svc.Record(ctx, evt)
+}
+
+func (svc *notificationService) List(ctx context.Context, uid int64, offset int, limit int) ([]domain.Notification, error) {
+	return svc.repo.FindByUid(ctx, uid, offset, limit)
+}
+
+func (svc *notificationService) UnreadCount(ctx context.Context, uid int64) (int64, error) {
+	return svc.repo.CountUnread(ctx, uid)
+}
+
+func (svc *notificationService) MarkRead(ctx context.Context, uid int64, id int64) error {
+	return svc.repo.MarkRead(ctx, uid, id)
+}
+
+func (svc *notificationService) MarkAllRead(ctx context.Context, uid int64) error {
+	return svc.repo.MarkAllRead(ctx, uid)
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"container/heap"
+	"context"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository"
+	"math"
+	"time"
+)
+
+const (
+	// readWeight is how much one read counts comparing to one like
+	readWeight = 0.1
+	// gravity is how fast the score decays with age, 1.8 is what Hacker News uses
+	gravity = 1.8
+)
+
+type RankingService interface {
+	// RankTopN computes the hot list and replaces the old one
+	RankTopN(ctx context.Context) error
+	// TopN returns the n hottest articles from the last computed list
+	TopN(ctx context.Context, n int) ([]domain.HotArticle, error)
+}
+
+type rankingService struct {
+	artRepo  repository.ArticleRepository
+	intrRepo repository.InteractiveRepository
+	repo     repository.RankingRepository
+
+	batchSize int
+	// n is how many articles are kept in the hot list
+	n int
+	// window is how old an article can be, older ones are hardly hot anyway
+	window time.Duration
+}
+
+func NewRankingService(artRepo repository.ArticleRepository,
+	intrRepo repository.InteractiveRepository,
+	repo repository.RankingRepository) RankingService {
+	return &rankingService{
+		artRepo:   artRepo,
+		intrRepo:  intrRepo,
+		repo:      repo,
+		batchSize: 100,
+		n:         100,
+		window:    time.Hour * 24 * 7,
+	}
+}
+
+func (svc *rankingService) TopN(ctx context.Context, n int) ([]domain.HotArticle, error) {
+	return svc.repo.GetTopN(ctx, n)
+}
+
+func (svc *rankingService) RankTopN(ctx context.Context) error {
+	now := time.Now()
+	since := now.Add(-svc.window)
+	// keeps the n hottest ones seen so far, the coldest on the top
+	h := &hotHeap{}
+	for offset := 0; ; offset += svc.batchSize {
+		arts, err := svc.artRepo.ListPub(ctx, since, offset, svc.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(arts) == 0 {
+			break
+		}
+		ids := make([]int64, 0, len(arts))
+		for _, art := range arts {
+			ids = append(ids, art.Id)
+		}
+		intrs, err := svc.intrRepo.GetByIds(ctx, domain.BizArticle, ids)
+		if err != nil {
+			return err
+		}
+		intrMap := make(map[int64]domain.Interactive, len(intrs))
+		for _, intr := range intrs {
+			intrMap[intr.BizId] = intr
+		}
+		for _, art := range arts {
+			hot := domain.HotArticle{
+				Article: art,
+				Score:   svc.score(intrMap[art.Id], art.CreatedAt, now),
+			}
+			if h.Len() < svc.n {
+				heap.Push(h, hot)
+			} else if hot.Score > (*h)[0].Score {
+				(*h)[0] = hot
+				heap.Fix(h, 0)
+			}
+		}
+		if len(arts) < svc.batchSize {
+			break
+		}
+	}
+	res := make([]domain.HotArticle, h.Len())
+	for i := len(res) - 1; i >= 0; i-- {
+		res[i] = heap.Pop(h).(domain.HotArticle)
+	}
+	return svc.repo.ReplaceTopN(ctx, res)
+}
+
+// score is the Hacker News formula, points / (age in hours + 2) ^ gravity
+func (svc *rankingService) score(intr domain.Interactive, publishedAt time.Time, now time.Time) float64 {
+	points := float64(intr.LikeCnt) + float64(intr.ReadCnt)*readWeight
+	hours := now.Sub(publishedAt).Hours()
+	if hours < 0 {
+		hours = 0
+	}
+	return points / math.Pow(hours+2, gravity)
+}
+
+// hotHeap is a min heap of the hot articles by score
+type hotHeap []domain.HotArticle
+
+func (h hotHeap) Len() int           { return len(h) }
+func (h hotHeap) Less(i, j int) bool { return h[i].Score < h[j].Score }
+func (h hotHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *hotHeap) Push(x any) {
+	*h = append(*h, x.(domain.HotArticle))
+}
+
+func (h *hotHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
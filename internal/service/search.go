@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository"
+	"github.com/webook/pkg/search"
+	"go.uber.org/zap"
+	"time"
+)
+
+type SearchService interface {
+	// Search finds the published articles and the users matching query
+	Search(ctx context.Context, query string, offset int, limit int) (domain.SearchResult, error)
+	// IndexArticle indexes the published article, replacing the old version.
+	// The index changes of IndexArticle, RemoveArticle and IndexUser are broadcast to the other replicas
+	IndexArticle(ctx context.Context, art domain.Article) error
+	RemoveArticle(ctx context.Context, id int64) error
+	// IndexUser indexes the latest profile of uid
+	IndexUser(ctx context.Context, uid int64) error
+	// Start follows the changes of the other replicas, and builds the indexes from DB in the background,
+	// the indexes may be in process
+	Start(ctx context.Context)
+}
+
+type searchService struct {
+	artIndex  search.Index
+	userIndex search.Index
+	artRepo   repository.ArticleRepository
+	userRepo  repository.UserRepository
+	indexRepo repository.SearchIndexRepository
+	// replica tells the changes made by this replica from the others
+	replica string
+	// batchSize is how many records are loaded at a time on start
+	batchSize int
+}
+
+func NewSearchService(artIndex search.Index, userIndex search.Index,
+	artRepo repository.ArticleRepository, userRepo repository.UserRepository,
+	indexRepo repository.SearchIndexRepository) SearchService {
+	return &searchService{
+		artIndex:  artIndex,
+		userIndex: userIndex,
+		artRepo:   artRepo,
+		userRepo:  userRepo,
+		indexRepo: indexRepo,
+		replica:   uuid.New().String(),
+		batchSize: 100,
+	}
+}
+
+func (svc *searchService) Search(ctx context.Context, query string, offset int, limit int) (domain.SearchResult, error) {
+	artHits, err := svc.artIndex.Search(ctx, query, offset, limit)
+	if err != nil {
+		return domain.SearchResult{}, err
+	}
+	userHits, err := svc.userIndex.Search(ctx, query, offset, limit)
+	if err != nil {
+		return domain.SearchResult{}, err
+	}
+	res := domain.SearchResult{
+		Articles: make([]domain.Article, 0, len(artHits)),
+		Users:    make([]domain.User, 0, len(userHits)),
+	}
+	for _, hit := range artHits {
+		art, err := svc.artRepo.GetPubById(ctx, hit.Id)
+		if err == ErrArticleNotFound {
+			continue
+		}
+		if err != nil {
+			return domain.SearchResult{}, err
+		}
+		if art.Status != domain.ArticleStatusPublished {
+			// withdrawn, and the change hasn't reached this replica yet
+			continue
+		}
+		res.Articles = append(res.Articles, art)
+	}
+	for _, hit := range userHits {
+		u, err := svc.userRepo.FindById(ctx, hit.Id)
+		if err == ErrUserNotFound {
+			continue
+		}
+		if err != nil {
+			return domain.SearchResult{}, err
+		}
+		res.Users = append(res.Users, u)
+	}
+	return res, nil
+}
+
+func (svc *searchService) IndexArticle(ctx context.Context, art domain.Article) error {
+	if err := svc.indexArticle(ctx, art); err != nil {
+		return err
+	}
+	return svc.broadcast(ctx, domain.SearchIndexChange{Kind: domain.SearchDocArticle, Id: art.Id})
+}
+
+func (svc *searchService) indexArticle(ctx context.Context, art domain.Article) error {
+	// the markup would match the searches for http, div or png
+	return svc.artIndex.Upsert(ctx, search.Document{
+		Id: art.Id,
+		Fields: map[string]string{
+			"title":   art.Title,
+			"content": art.PlainText(),
+		},
+	})
+}
+
+func (svc *searchService) RemoveArticle(ctx context.Context, id int64) error {
+	if err := svc.artIndex.Delete(ctx, id); err != nil {
+		return err
+	}
+	return svc.broadcast(ctx, domain.SearchIndexChange{Kind: domain.SearchDocArticle, Id: id, Removed: true})
+}
+
+func (svc *searchService) IndexUser(ctx context.Context, uid int64) error {
+	u, err := svc.userRepo.FindById(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if err = svc.indexUser(ctx, u); err != nil {
+		return err
+	}
+	return svc.broadcast(ctx, domain.SearchIndexChange{Kind: domain.SearchDocUser, Id: uid})
+}
+
+func (svc *searchService) broadcast(ctx context.Context, change domain.SearchIndexChange) error {
+	change.Origin = svc.replica
+	return svc.indexRepo.Publish(ctx, change)
+}
+
+// apply reloads the document changed by another replica from DB
+func (svc *searchService) apply(ctx context.Context, change domain.SearchIndexChange) error {
+	switch change.Kind {
+	case domain.SearchDocArticle:
+		if change.Removed {
+			return svc.artIndex.Delete(ctx, change.Id)
+		}
+		art, err := svc.artRepo.GetPubById(ctx, change.Id)
+		if err == ErrArticleNotFound {
+			return svc.artIndex.Delete(ctx, change.Id)
+		}
+		if err != nil {
+			return err
+		}
+		if art.Status != domain.ArticleStatusPublished {
+			return svc.artIndex.Delete(ctx, change.Id)
+		}
+		return svc.indexArticle(ctx, art)
+	case domain.SearchDocUser:
+		u, err := svc.userRepo.FindById(ctx, change.Id)
+		if err != nil {
+			return err
+		}
+		return svc.indexUser(ctx, u)
+	default:
+		return nil
+	}
+}
+
+func (svc *searchService) indexUser(ctx context.Context, u domain.User) error {
+	return svc.userIndex.Upsert(ctx, search.Document{
+		Id: u.Id,
+		Fields: map[string]string{
+			"nickname": u.Nickname,
+			"aboutMe":  u.AboutMe,
+		},
+	})
+}
+
+func (svc *searchService) Start(ctx context.Context) {
+	// subscribe before loading, so that the changes made meanwhile are not missed
+	changes := svc.indexRepo.Subscribe(ctx)
+	go func() {
+		for change := range changes {
+			if change.Origin == svc.replica {
+				continue
+			}
+			if err := svc.apply(ctx, change); err != nil {
+				zap.L().Error("Failed to apply search index change",
+					zap.String("kind", change.Kind), zap.Int64("id", change.Id), zap.Error(err))
+			}
+		}
+	}()
+	go func() {
+		start := time.Now()
+		if err := svc.loadArticles(ctx); err != nil {
+			zap.L().Error("Failed to index articles", zap.Error(err))
+		}
+		if err := svc.loadUsers(ctx); err != nil {
+			zap.L().Error("Failed to index users", zap.Error(err))
+		}
+		zap.L().Info("Search indexes built", zap.Duration("duration", time.Since(start)))
+	}()
+}
+
+func (svc *searchService) loadArticles(ctx context.Context) error {
+	for offset := 0; ; offset += svc.batchSize {
+		arts, err := svc.artRepo.ListPub(ctx, time.UnixMilli(0), offset, svc.batchSize)
+		if err != nil {
+			return err
+		}
+		for _, art := range arts {
+			if err = svc.indexArticle(ctx, art); err != nil {
+				return err
+			}
+		}
+		if len(arts) < svc.batchSize {
+			return nil
+		}
+	}
+}
+
+func (svc *searchService) loadUsers(ctx context.Context) error {
+	for offset := 0; ; offset += svc.batchSize {
+		users, err := svc.userRepo.List(ctx, offset, svc.batchSize)
+		if err != nil {
+			return err
+		}
+		for _, u := range users {
+			if err = svc.indexUser(ctx, u); err != nil {
+				return err
+			}
+		}
+		if len(users) < svc.batchSize {
+			return nil
+		}
+	}
+}
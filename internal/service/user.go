@@ -3,34 +3,95 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/webook/internal/domain"
 	"github.com/webook/internal/repository"
+	"github.com/webook/pkg/eventbus"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"time"
 )
 
 var (
 	ErrDuplicateEmail        = repository.ErrDuplicateUser
 	ErrInvalidUserOrPassword = errors.New("User doesn't exist or password is not correct")
+	ErrUserNotFound          = repository.ErrUserNotfound
+	ErrPhoneBoundToOther     = repository.ErrDuplicatePhone
+	ErrWechatBoundToOther    = repository.ErrDuplicateWechat
+	ErrWechatNotLinked       = errors.New("Wechat is not linked")
+	ErrWechatOnlyLogin       = errors.New("Wechat is the only way to log in")
+	ErrIncorrectPassword     = errors.New("Password is not correct")
+	ErrEmailNotVerified      = errors.New("Please verify your email first")
 )
 
+// the actions UnverifiedUserPolicy may limit
+const (
+	UserActionPublish = "publish"
+	UserActionComment = "comment"
+)
+
+// UnverifiedUserPolicy is what the users with unverified emails can do
+type UnverifiedUserPolicy struct {
+	Publish bool `yaml:"publish"`
+	Comment bool `yaml:"comment"`
+}
+
+func (p UnverifiedUserPolicy) allows(action string) bool {
+	switch action {
+	case UserActionPublish:
+		return p.Publish
+	case UserActionComment:
+		return p.Comment
+	default:
+		return true
+	}
+}
+
 type UserService interface {
 	Signup(ctx context.Context, u domain.User) error
 	Login(ctx context.Context, email string, password string) (domain.User, error)
 	UpdateNonSensitiveInfo(ctx context.Context, user domain.User) error
 	FindById(ctx context.Context, uid int64) (domain.User, error)
+	// FindByIds returns the users by id, the ones not found are skipped
+	FindByIds(ctx context.Context, uids []int64) (map[int64]domain.User, error)
 	FindOrCreate(ctx context.Context, phone string) (domain.User, error)
 	FindOrCreateByWechat(ctx context.Context, info domain.WechatInfo) (domain.User, error)
+	FindByEmail(ctx context.Context, email string) (domain.User, error)
+	FindByPhone(ctx context.Context, phone string) (domain.User, error)
+	// ResetPassword sets the password of the user with the email, whose code is verified already
+	ResetPassword(ctx context.Context, email string, password string) (domain.User, error)
+	// ChangePassword sets the new password if the old one is correct, otherwise returns ErrIncorrectPassword
+	ChangePassword(ctx context.Context, uid int64, oldPassword string, newPassword string) error
+	// MarkEmailVerified is called once the user proves owning the email
+	MarkEmailVerified(ctx context.Context, uid int64) error
+	// BindPhone binds the verified phone to uid, replacing the old one,
+	// it returns ErrPhoneBoundToOther if another account has the phone
+	BindPhone(ctx context.Context, uid int64, phone string) error
+	// BindWechat links the Wechat account to uid, replacing the old one,
+	// it returns ErrWechatBoundToOther if another account has it
+	BindWechat(ctx context.Context, uid int64, info domain.WechatInfo) error
+	// UnlinkWechat returns ErrWechatOnlyLogin if the user can't log in without Wechat
+	UnlinkWechat(ctx context.Context, uid int64) error
+	// CheckAllowed returns ErrEmailNotVerified if uid can't do the action until verifying the email
+	CheckAllowed(ctx context.Context, uid int64, action string) error
 }
 
 type userService struct {
-	repo repository.UserRepository
+	repo       repository.UserRepository
+	bus        eventbus.Bus
+	searchSvc  SearchService
+	unverified UnverifiedUserPolicy
 	//logger *zap.Logger
 }
 
-func NewUserService(repo repository.UserRepository) UserService {
+func NewUserService(repo repository.UserRepository, bus eventbus.Bus, searchSvc SearchService,
+	unverified UnverifiedUserPolicy) UserService {
 	return &userService{
-		repo: repo,
+		repo:       repo,
+		bus:        bus,
+		searchSvc:  searchSvc,
+		unverified: unverified,
 		//logger: zap.L(),
 	}
 }
@@ -60,12 +121,166 @@ func (svc *userService) Login(ctx context.Context, email string, password string
 	return user, nil
 }
 
+func (svc *userService) FindByEmail(ctx context.Context, email string) (domain.User, error) {
+	return svc.repo.FindByEmail(ctx, email)
+}
+
+func (svc *userService) FindByPhone(ctx context.Context, phone string) (domain.User, error) {
+	return svc.repo.FindByPhone(ctx, phone)
+}
+
+func (svc *userService) ResetPassword(ctx context.Context, email string, password string) (domain.User, error) {
+	u, err := svc.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return domain.User{}, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return domain.User{}, err
+	}
+	return u, svc.repo.UpdatePassword(ctx, u.Id, string(hash))
+}
+
+func (svc *userService) ChangePassword(ctx context.Context, uid int64, oldPassword string, newPassword string) error {
+	u, err := svc.repo.FindById(ctx, uid)
+	if err != nil {
+		return err
+	}
+	// the users signed up by SMS or WeChat have no password, nothing matches it
+	err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(oldPassword))
+	if err != nil {
+		return ErrIncorrectPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return svc.repo.UpdatePassword(ctx, uid, string(hash))
+}
+
+func (svc *userService) MarkEmailVerified(ctx context.Context, uid int64) error {
+	return svc.repo.MarkEmailVerified(ctx, uid)
+}
+
+func (svc *userService) BindPhone(ctx context.Context, uid int64, phone string) error {
+	u, err := svc.repo.FindByPhone(ctx, phone)
+	switch err {
+	case nil:
+		if u.Id != uid {
+			return ErrPhoneBoundToOther
+		}
+		// bound already
+		return nil
+	case repository.ErrUserNotfound:
+	default:
+		return err
+	}
+	// the unique index rejects it if another account binds it in between
+	err = svc.repo.UpdatePhone(ctx, uid, phone)
+	if err != nil {
+		return err
+	}
+	svc.notify(ctx, uid, domain.NotificationKindPhoneBound, fmt.Sprintf("Phone %s is bound to your account", maskPhone(phone)))
+	return nil
+}
+
+func (svc *userService) BindWechat(ctx context.Context, uid int64, info domain.WechatInfo) error {
+	u, err := svc.repo.FindByWechat(ctx, info.OpenId)
+	switch err {
+	case nil:
+		if u.Id != uid {
+			return ErrWechatBoundToOther
+		}
+		// linked already
+		return nil
+	case repository.ErrUserNotfound:
+	default:
+		return err
+	}
+	err = svc.repo.UpdateWechat(ctx, uid, info)
+	if err != nil {
+		return err
+	}
+	svc.notify(ctx, uid, domain.NotificationKindWechatLinked, "Wechat is linked to your account")
+	return nil
+}
+
+func (svc *userService) UnlinkWechat(ctx context.Context, uid int64) error {
+	u, err := svc.repo.FindById(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if u.WechatInfo.OpenId == "" {
+		return ErrWechatNotLinked
+	}
+	if !u.HasLoginBesidesWechat() {
+		return ErrWechatOnlyLogin
+	}
+	return svc.repo.UpdateWechat(ctx, uid, domain.WechatInfo{})
+}
+
+// maskPhone keeps the last 4 digits only
+func maskPhone(phone string) string {
+	if len(phone) <= 4 {
+		return phone
+	}
+	return strings.Repeat("*", len(phone)-4) + phone[len(phone)-4:]
+}
+
+func (svc *userService) CheckAllowed(ctx context.Context, uid int64, action string) error {
+	if svc.unverified.allows(action) {
+		return nil
+	}
+	u, err := svc.repo.FindById(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if u.EmailUnverified() {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+func (svc *userService) FindByIds(ctx context.Context, uids []int64) (map[int64]domain.User, error) {
+	us, err := svc.repo.FindByIds(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int64]domain.User, len(us))
+	for _, u := range us {
+		res[u.Id] = u
+	}
+	return res, nil
+}
+
 func (svc *userService) FindById(ctx context.Context, uid int64) (domain.User, error) {
 	return svc.repo.FindById(ctx, uid) // why pass uid here and return domain.User?
 }
 
 func (svc *userService) UpdateNonSensitiveInfo(ctx context.Context, user domain.User) error {
-	return svc.repo.UpdateNonZeroFields(ctx, user)
+	err := svc.repo.UpdateNonZeroFields(ctx, user)
+	if err != nil {
+		return err
+	}
+	if err = svc.searchSvc.IndexUser(ctx, user.Id); err != nil {
+		// it is found once the index is rebuilt
+		zap.L().Error("Failed to index user", zap.Int64("uid", user.Id), zap.Error(err))
+	}
+	svc.notify(ctx, user.Id, domain.NotificationKindProfileUpdated, "Your profile was updated")
+	return nil
+}
+
+// notify sends a notification to uid, failing to notify doesn't fail the operation
+func (svc *userService) notify(ctx context.Context, uid int64, kind string, content string) {
+	err := svc.bus.Publish(ctx, domain.TopicNotification, domain.NotificationEvent{
+		Uid:     uid,
+		Kind:    kind,
+		Content: content,
+		Time:    time.Now(),
+	})
+	if err != nil {
+		zap.L().Error("Failed to notify user", zap.Int64("uid", uid), zap.String("kind", kind), zap.Error(err))
+	}
 }
 
 func (svc *userService) FindOrCreate(ctx context.Context, phone string) (domain.User, error) {
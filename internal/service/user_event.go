@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type UserEventService interface {
+	// Publish pushes evt to the open streams of evt.Uid on all replicas
+	Publish(ctx context.Context, evt domain.UserEvent) error
+	// Subscribe streams the events of uid until ctx is done. If lastId is given,
+	// the events after it are replayed first. The channel is closed as well
+	// when the subscriber falls behind, the client is expected to reconnect with the last id it got
+	Subscribe(ctx context.Context, uid int64, lastId string) (<-chan domain.UserEvent, error)
+	// Start dispatches the events broadcast by all replicas to the local subscribers until ctx is done
+	Start(ctx context.Context)
+	// HandleEvent adapts Publish to the event bus
+	HandleEvent(ctx context.Context, payload any) error
+	// HandleNotification pushes the notifications to the streams as well
+	HandleNotification(ctx context.Context, payload any) error
+}
+
+type userEventService struct {
+	repo repository.UserEventRepository
+
+	lock sync.Mutex
+	subs map[int64]map[*userEventSubscriber]struct{}
+	// bufferSize is how many events a subscriber can fall behind
+	bufferSize int
+}
+
+type userEventSubscriber struct {
+	ch     chan domain.UserEvent
+	closed bool
+}
+
+func NewUserEventService(repo repository.UserEventRepository) UserEventService {
+	return &userEventService{
+		repo:       repo,
+		subs:       make(map[int64]map[*userEventSubscriber]struct{}),
+		bufferSize: 64,
+	}
+}
+
+func (svc *userEventService) Publish(ctx context.Context, evt domain.UserEvent) error {
+	_, err := svc.repo.Append(ctx, evt)
+	return err
+}
+
+func (svc *userEventService) HandleEvent(ctx context.Context, payload any) error {
+	evt, ok := payload.(domain.UserEvent)
+	if !ok {
+		return fmt.Errorf("unexpected user event payload %T", payload)
+	}
+	return svc.Publish(ctx, evt)
+}
+
+func (svc *userEventService) HandleNotification(ctx context.Context, payload any) error {
+	evt, ok := payload.(domain.NotificationEvent)
+	if !ok {
+		return fmt.Errorf("unexpected notification payload %T", payload)
+	}
+	data, err := json.Marshal(map[string]any{
+		"kind":    evt.Kind,
+		"content": evt.Content,
+		"time":    evt.Time.UnixMilli(),
+	})
+	if err != nil {
+		return err
+	}
+	return svc.Publish(ctx, domain.UserEvent{
+		Uid:  evt.Uid,
+		Type: "notification",
+		Data: string(data),
+	})
+}
+
+func (svc *userEventService) Subscribe(ctx context.Context, uid int64, lastId string) (<-chan domain.UserEvent, error) {
+	// subscribe before reading the history, so that nothing is missed in between
+	sub := svc.subscribe(uid)
+	var history []domain.UserEvent
+	if _, _, ok := parseUserEventId(lastId); ok {
+		var err error
+		history, err = svc.repo.After(ctx, uid, lastId)
+		if err != nil {
+			svc.unsubscribe(uid, sub)
+			return nil, err
+		}
+	} else {
+		// unknown id, only the new events then
+		lastId = ""
+	}
+	res := make(chan domain.UserEvent)
+	go func() {
+		defer close(res)
+		defer svc.unsubscribe(uid, sub)
+		send := func(evt domain.UserEvent) bool {
+			select {
+			case res <- evt:
+				lastId = evt.Id
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for _, evt := range history {
+			if !send(evt) {
+				return
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case evt, ok := <-sub.ch:
+				if !ok {
+					return
+				}
+				// it may be in the history already
+				if lastId != "" && !userEventIdAfter(evt.Id, lastId) {
+					continue
+				}
+				if !send(evt) {
+					return
+				}
+			}
+		}
+	}()
+	return res, nil
+}
+
+func (svc *userEventService) Start(ctx context.Context) {
+	events := svc.repo.Subscribe(ctx)
+	go func() {
+		for evt := range events {
+			svc.dispatch(evt)
+		}
+	}()
+}
+
+func (svc *userEventService) dispatch(evt domain.UserEvent) {
+	svc.lock.Lock()
+	defer svc.lock.Unlock()
+	for sub := range svc.subs[evt.Uid] {
+		select {
+		case sub.ch <- evt:
+		default:
+			// too slow, drop it rather than block the others
+			sub.closed = true
+			close(sub.ch)
+			delete(svc.subs[evt.Uid], sub)
+		}
+	}
+}
+
+func (svc *userEventService) subscribe(uid int64) *userEventSubscriber {
+	sub := &userEventSubscriber{
+		ch: make(chan domain.UserEvent, svc.bufferSize),
+	}
+	svc.lock.Lock()
+	defer svc.lock.Unlock()
+	subs, ok := svc.subs[uid]
+	if !ok {
+		subs = make(map[*userEventSubscriber]struct{})
+		svc.subs[uid] = subs
+	}
+	subs[sub] = struct{}{}
+	return sub
+}
+
+func (svc *userEventService) unsubscribe(uid int64, sub *userEventSubscriber) {
+	svc.lock.Lock()
+	defer svc.lock.Unlock()
+	if !sub.closed {
+		sub.closed = true
+		close(sub.ch)
+	}
+	delete(svc.subs[uid], sub)
+	if len(svc.subs[uid]) == 0 {
+		delete(svc.subs, uid)
+	}
+}
+
+// parseUserEventId parses the Redis stream ids, like 1700000000000-0
+func parseUserEventId(id string) (uint64, uint64, bool) {
+	ms, seq, found := strings.Cut(id, "-")
+	if !found {
+		return 0, 0, false
+	}
+	msVal, err := strconv.ParseUint(ms, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	seqVal, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return msVal, seqVal, true
+}
+
+func userEventIdAfter(id string, other string) bool {
+	ms, seq, _ := parseUserEventId(id)
+	otherMs, otherSeq, _ := parseUserEventId(other)
+	if ms != otherMs {
+		return ms > otherMs
+	}
+	return seq > otherSeq
+}
@@ -1,15 +1,871 @@
 package web
 
-import "gorm.io/gorm/logger"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/service"
+	ijwt "github.com/webook/internal/web/jwt"
+	"github.com/webook/pkg/logger"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var _ Handler = &ArticleHandler{}
+
+// maxPageSize prevents listing too many articles in one request
+const maxPageSize = 100
 
 type ArticleHandler struct {
-	svc service.ArticleService
-	l   logger.LoggerV1
+	svc        service.ArticleService
+	intrSvc    service.InteractiveService
+	rankingSvc service.RankingService
+	commentSvc service.CommentService
+	l          logger.LoggerV1
 }
 
-func NewArticleHandler(l logger.LoggerV1, svc service.ArticleService) *ArticleHandler {
+func NewArticleHandler(l logger.LoggerV1, svc service.ArticleService,
+	intrSvc service.InteractiveService, rankingSvc service.RankingService,
+	commentSvc service.CommentService) *ArticleHandler {
 	return &ArticleHandler{
-		l:   l,
-		svc: svc,
+		l:          l,
+		svc:        svc,
+		intrSvc:    intrSvc,
+		rankingSvc: rankingSvc,
+		commentSvc: commentSvc,
+	}
+}
+
+func (h *ArticleHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/articles")
+	g.POST("/edit", h.Edit)
+	g.GET("/detail/:id", h.Detail)
+	g.POST("/publish", h.Publish)
+	g.POST("/withdraw", h.Withdraw)
+	g.POST("/list", h.List)
+	g.GET("/revisions/:id", h.Revisions)
+	g.GET("/revisions/:id/diff", h.DiffRevisions)
+	g.POST("/revisions/restore", h.RestoreRevision)
+	g.POST("/schedule", h.Schedule)
+	g.POST("/schedule/cancel", h.CancelSchedule)
+	g.GET("/hot", h.Hot)
+
+	pub := server.Group("/pub")
+	pub.GET("/:id", h.PubDetail)
+	pub.POST("/like", h.Like)
+	pub.POST("/collect", h.Collect)
+
+	tg := server.Group("/tags")
+	tg.GET("/popular", h.PopularTags)
+	tg.GET("/:tag/articles", h.ListByTag)
+}
+
+// Edit creates or updates the draft of the logged-in author
+func (h *ArticleHandler) Edit(ctx *gin.Context) {
+	var req ArticleReq
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	id, err := h.svc.Save(ctx, req.toDomain(uc.Uid))
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Data: id,
+		})
+	case service.ErrTooManyTags:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Too Many Tags",
+		})
+	case service.ErrInvalidFormat:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Unknown Content Format",
+		})
+	case service.ErrArticleNotFound:
+		// if lots of this warning, someone is trying to edit other's articles
+		h.l.Warn("Failed to save article, article not found or author not match",
+			logger.Int64("aid", req.Id),
+			logger.Int64("uid", uc.Uid))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Article Not Found",
+		})
+	default:
+		h.l.Error("Failed to save article",
+			logger.Int64("aid", req.Id),
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+	}
+}
+
+// Publish saves the draft and publishes it to readers
+func (h *ArticleHandler) Publish(ctx *gin.Context) {
+	var req ArticleReq
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	id, err := h.svc.Publish(ctx, req.toDomain(uc.Uid))
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Data: id,
+		})
+	case service.ErrTooManyTags:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Too Many Tags",
+		})
+	case service.ErrInvalidFormat:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Unknown Content Format",
+		})
+	case service.ErrEmailNotVerified:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Please Verify Your Email Before Publishing",
+		})
+	case service.ErrArticleNotFound:
+		h.l.Warn("Failed to publish article, article not found or author not match",
+			logger.Int64("aid", req.Id),
+			logger.Int64("uid", uc.Uid))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Article Not Found",
+		})
+	default:
+		h.l.Error("Failed to publish article",
+			logger.Int64("aid", req.Id),
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+	}
+}
+
+// Withdraw makes the article private, readers can no longer see it
+func (h *ArticleHandler) Withdraw(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	err := h.svc.Withdraw(ctx, uc.Uid, req.Id)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "OK",
+		})
+	case service.ErrArticleNotFound:
+		h.l.Warn("Failed to withdraw article, article not found or author not match",
+			logger.Int64("aid", req.Id),
+			logger.Int64("uid", uc.Uid))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Article Not Found",
+		})
+	default:
+		h.l.Error("Failed to withdraw article",
+			logger.Int64("aid", req.Id),
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+	}
+}
+
+// List returns the logged-in author's articles, abstract only
+func (h *ArticleHandler) List(ctx *gin.Context) {
+	var page Page
+	if err := ctx.Bind(&page); err != nil {
+		return
+	}
+	if page.Limit <= 0 || page.Limit > maxPageSize || page.Offset < 0 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Page Size",
+		})
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	arts, err := h.svc.List(ctx, uc.Uid, page.Offset, page.Limit)
+	if err != nil {
+		h.l.Error("Failed to list articles",
+			logger.Int64("uid", uc.Uid),
+			logger.Int("offset", page.Offset),
+			logger.Int("limit", page.Limit),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: h.toAbstractVos(arts),
+	})
+}
+
+// ListByTag lists the published articles with the tag
+func (h *ArticleHandler) ListByTag(ctx *gin.Context) {
+	var page Page
+	if err := ctx.ShouldBindQuery(&page); err != nil ||
+		page.Limit <= 0 || page.Limit > maxPageSize || page.Offset < 0 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Page Size",
+		})
+		return
+	}
+	tag := ctx.Param("tag")
+	arts, err := h.svc.ListByTag(ctx, tag, page.Offset, page.Limit)
+	if err != nil {
+		h.l.Error("Failed to list articles by tag",
+			logger.String("tag", tag),
+			logger.Int("offset", page.Offset),
+			logger.Int("limit", page.Limit),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: h.toAbstractVos(arts),
+	})
+}
+
+// PopularTags returns the tags used by most published articles
+func (h *ArticleHandler) PopularTags(ctx *gin.Context) {
+	n, err := strconv.Atoi(ctx.DefaultQuery("n", "10"))
+	if err != nil || n <= 0 || n > maxPageSize {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Number Of Tags",
+		})
+		return
+	}
+	tags, err := h.svc.PopularTags(ctx, n)
+	if err != nil {
+		h.l.Error("Failed to get popular tags", logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	res := make([]TagVo, 0, len(tags))
+	for _, tag := range tags {
+		res = append(res, TagVo{
+			Name:  tag.Name,
+			Count: tag.Count,
+		})
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+// Hot returns the n hottest published articles, abstract only
+func (h *ArticleHandler) Hot(ctx *gin.Context) {
+	n, err := strconv.Atoi(ctx.DefaultQuery("n", "10"))
+	if err != nil || n <= 0 || n > maxPageSize {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Number Of Articles",
+		})
+		return
+	}
+	hots, err := h.rankingSvc.TopN(ctx, n)
+	if err != nil {
+		h.l.Error("Failed to get hot articles", logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	res := make([]HotArticleVo, 0, len(hots))
+	for _, hot := range hots {
+		art := hot.Article
+		res = append(res, HotArticleVo{
+			ArticleVo: ArticleVo{
+				Id:       art.Id,
+				Title:    art.Title,
+				Abstract: art.Abstract(),
+				AuthorId: art.Author.Id,
+				Category: art.Category,
+				Tags:     art.Tags,
+				Ctime:    art.CreatedAt.Format(time.DateTime),
+				Utime:    art.UpdatedAt.Format(time.DateTime),
+			},
+			Score: hot.Score,
+		})
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+// toAbstractVos is for the lists, which only show the abstracts
+func (h *ArticleHandler) toAbstractVos(arts []domain.Article) []ArticleVo {
+	res := make([]ArticleVo, 0, len(arts))
+	for _, art := range arts {
+		res = append(res, ArticleVo{
+			Id:       art.Id,
+			Title:    art.Title,
+			Abstract: art.Abstract(),
+			AuthorId: art.Author.Id,
+			Status:   art.Status.ToUint8(),
+			Category: art.Category,
+			Tags:     art.Tags,
+			Ctime:    art.CreatedAt.Format(time.DateTime),
+			Utime:    art.UpdatedAt.Format(time.DateTime),
+		})
+	}
+	return res
+}
+
+// Revisions lists the revisions of the author's article, the newest first
+func (h *ArticleHandler) Revisions(ctx *gin.Context) {
+	aid, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Article Id",
+		})
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	revs, err := h.svc.ListRevisions(ctx, uc.Uid, aid)
+	switch err {
+	case nil:
+		res := make([]ArticleRevisionVo, 0, len(revs))
+		for _, rev := range revs {
+			res = append(res, ArticleRevisionVo{
+				Revision: rev.Revision,
+				Title:    rev.Title,
+				Ctime:    rev.CreatedAt.Format(time.DateTime),
+			})
+		}
+		ctx.JSON(http.StatusOK, Result{
+			Data: res,
+		})
+	case service.ErrArticleNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Article Not Found",
+		})
+	default:
+		h.l.Error("Failed to list article revisions",
+			logger.Int64("aid", aid),
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+	}
+}
+
+// DiffRevisions returns the unified diff between revision from and revision to
+func (h *ArticleHandler) DiffRevisions(ctx *gin.Context) {
+	aid, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Article Id",
+		})
+		return
+	}
+	from, err := strconv.ParseInt(ctx.Query("from"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Revision",
+		})
+		return
+	}
+	to, err := strconv.ParseInt(ctx.Query("to"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Revision",
+		})
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	diff, err := h.svc.DiffRevisions(ctx, uc.Uid, aid, from, to)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Data: diff,
+		})
+	case service.ErrArticleNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Article Not Found",
+		})
+	case service.ErrRevisionNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Revision Not Found",
+		})
+	default:
+		h.l.Error("Failed to diff article revisions",
+			logger.Int64("aid", aid),
+			logger.Int64("from", from),
+			logger.Int64("to", to),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+	}
+}
+
+// RestoreRevision saves an old revision as the current draft
+func (h *ArticleHandler) RestoreRevision(ctx *gin.Context) {
+	type Req struct {
+		Id       int64 `json:"id"`
+		Revision int64 `json:"revision"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	err := h.svc.RestoreRevision(ctx, uc.Uid, req.Id, req.Revision)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "OK",
+		})
+	case service.ErrArticleNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Article Not Found",
+		})
+	case service.ErrRevisionNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Revision Not Found",
+		})
+	default:
+		h.l.Error("Failed to restore article revision",
+			logger.Int64("aid", req.Id),
+			logger.Int64("revision", req.Revision),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+	}
+}
+
+// Schedule publishes the draft at a future time
+func (h *ArticleHandler) Schedule(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+		// UTC 0 millisecond
+		PublishAt int64 `json:"publishAt"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	err := h.svc.Schedule(ctx, uc.Uid, req.Id, time.UnixMilli(req.PublishAt))
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "OK",
+		})
+	case service.ErrInvalidPublishAt:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Publish Time Must Be In The Future",
+		})
+	case service.ErrEmailNotVerified:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Please Verify Your Email Before Publishing",
+		})
+	case service.ErrArticleNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Article Not Found",
+		})
+	default:
+		h.l.Error("Failed to schedule article",
+			logger.Int64("aid", req.Id),
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+	}
+}
+
+// CancelSchedule turns the scheduled article back into a draft
+func (h *ArticleHandler) CancelSchedule(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	err := h.svc.CancelSchedule(ctx, uc.Uid, req.Id)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "OK",
+		})
+	case service.ErrArticleNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Scheduled Article Not Found",
+		})
+	default:
+		h.l.Error("Failed to cancel scheduled article",
+			logger.Int64("aid", req.Id),
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+	}
+}
+
+// Detail returns the draft to its author
+func (h *ArticleHandler) Detail(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Article Id",
+		})
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	art, err := h.svc.GetById(ctx, id)
+	if err == service.ErrArticleNotFound {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Article Not Found",
+		})
+		return
+	}
+	if err != nil {
+		h.l.Error("Failed to get article",
+			logger.Int64("aid", id),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	if art.Author.Id != uc.Uid {
+		// do not tell the caller the article exists
+		h.l.Warn("Illegal access to other's article",
+			logger.Int64("aid", id),
+			logger.Int64("uid", uc.Uid))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Article Not Found",
+		})
+		return
+	}
+	vo := ArticleVo{
+		Id:             art.Id,
+		Title:          art.Title,
+		Content:        art.Content,
+		Format:         art.Format.ToUint8(),
+		Html:           art.HTML(),
+		WordCount:      art.WordCount(),
+		ReadingMinutes: int(art.ReadingTime().Minutes()),
+		AuthorId:       art.Author.Id,
+		Status:         art.Status.ToUint8(),
+		Category:       art.Category,
+		Tags:           art.Tags,
+		Ctime:          art.CreatedAt.Format(time.DateTime),
+		Utime:          art.UpdatedAt.Format(time.DateTime),
+	}
+	if art.Status == domain.ArticleStatusScheduled {
+		vo.PublishAt = art.PublishAt.Format(time.DateTime)
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: vo,
+	})
+}
+
+// PubDetail returns the published article to readers, with its interactive counters
+func (h *ArticleHandler) PubDetail(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Article Id",
+		})
+		return
+	}
+	// readers don't have to log in, uc.Uid is 0 then
+	var uc ijwt.UserClaims
+	if val, ok := ctx.Get("user"); ok {
+		uc, _ = val.(ijwt.UserClaims)
+	}
+	art, err := h.svc.GetPubById(ctx, id)
+	switch err {
+	case nil:
+	case service.ErrArticleNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Article Not Found",
+		})
+		return
+	default:
+		h.l.Error("Failed to get published article",
+			logger.Int64("aid", id),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	// losing a read or two is fine, don't fail the request for it
+	if err = h.intrSvc.IncrReadCnt(ctx, domain.BizArticle, id, h.reader(ctx, uc.Uid)); err != nil {
+		h.l.Error("Failed to increase read count",
+			logger.Int64("aid", id),
+			logger.Error(err))
+	}
+	intr, err := h.intrSvc.Get(ctx, domain.BizArticle, id, uc.Uid)
+	if err != nil {
+		// still show the article, without the counters
+		h.l.Error("Failed to get interactive counters",
+			logger.Int64("aid", id),
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+	}
+	commentCnt, err := h.commentSvc.Count(ctx, id)
+	if err != nil {
+		h.l.Error("Failed to count comments",
+			logger.Int64("aid", id),
+			logger.Error(err))
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: ArticleVo{
+			Id:             art.Id,
+			Title:          art.Title,
+			Content:        art.Content,
+			Format:         art.Format.ToUint8(),
+			Html:           art.HTML(),
+			WordCount:      art.WordCount(),
+			ReadingMinutes: int(art.ReadingTime().Minutes()),
+			AuthorId:       art.Author.Id,
+			AuthorName:     art.Author.Name,
+			Status:         art.Status.ToUint8(),
+			Category:       art.Category,
+			Tags:           art.Tags,
+			Ctime:          art.CreatedAt.Format(time.DateTime),
+			Utime:          art.UpdatedAt.Format(time.DateTime),
+			ReadCnt:        intr.ReadCnt,
+			UniqueReadCnt:  intr.UniqueReadCnt,
+			LikeCnt:        intr.LikeCnt,
+			CollectCnt:     intr.CollectCnt,
+			Liked:          intr.Liked,
+			Collected:      intr.Collected,
+			CommentCnt:     commentCnt,
+		},
+	})
+}
+
+// reader identifies who is reading, logged-in readers by uid,
+// anonymous ones by the device id from the client, or a fingerprint of IP and User-Agent
+func (h *ArticleHandler) reader(ctx *gin.Context, uid int64) string {
+	if uid > 0 {
+		return "u:" + strconv.FormatInt(uid, 10)
+	}
+	if deviceId := ctx.GetHeader("X-Device-Id"); deviceId != "" {
+		return "d:" + deviceId
+	}
+	sum := sha256.Sum256([]byte(ctx.ClientIP() + "|" + ctx.Request.UserAgent()))
+	return "f:" + hex.EncodeToString(sum[:16])
+}
+
+// Like likes or cancels the like of a published article, liking twice is a no-op
+func (h *ArticleHandler) Like(ctx *gin.Context) {
+	type Req struct {
+		Id   int64 `json:"id"`
+		Like bool  `json:"like"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	if !h.checkPublished(ctx, req.Id) {
+		return
+	}
+	var err error
+	if req.Like {
+		err = h.intrSvc.Like(ctx, domain.BizArticle, req.Id, uc.Uid)
+	} else {
+		err = h.intrSvc.CancelLike(ctx, domain.BizArticle, req.Id, uc.Uid)
+	}
+	if err != nil {
+		h.l.Error("Failed to like article",
+			logger.Int64("aid", req.Id),
+			logger.Int64("uid", uc.Uid),
+			logger.Bool("like", req.Like),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "OK",
+	})
+}
+
+// Collect collects or cancels the collection of a published article, collecting twice is a no-op.
+// Cid is the collection to put it in, 0 is the default one, collecting again into another collection moves it
+func (h *ArticleHandler) Collect(ctx *gin.Context) {
+	type Req struct {
+		Id      int64 `json:"id"`
+		Collect bool  `json:"collect"`
+		Cid     int64 `json:"cid"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	if !h.checkPublished(ctx, req.Id) {
+		return
+	}
+	var err error
+	if req.Collect {
+		err = h.intrSvc.Collect(ctx, domain.BizArticle, req.Id, uc.Uid, req.Cid)
+	} else {
+		err = h.intrSvc.CancelCollect(ctx, domain.BizArticle, req.Id, uc.Uid)
+	}
+	if err == service.ErrCollectionNotFound {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Collection Not Found",
+		})
+		return
+	}
+	if err != nil {
+		h.l.Error("Failed to collect article",
+			logger.Int64("aid", req.Id),
+			logger.Int64("uid", uc.Uid),
+			logger.Bool("collect", req.Collect),
+			logger.Int64("cid", req.Cid),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "OK",
+	})
+}
+
+// checkPublished writes the response and returns false if readers can't see the article
+func (h *ArticleHandler) checkPublished(ctx *gin.Context, id int64) bool {
+	_, err := h.svc.GetPubById(ctx, id)
+	switch err {
+	case nil:
+		return true
+	case service.ErrArticleNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Article Not Found",
+		})
+	default:
+		h.l.Error("Failed to get published article",
+			logger.Int64("aid", id),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
 	}
+	return false
 }
@@ -1,13 +1,71 @@
 package web
 
+import "github.com/webook/internal/domain"
+
+type ArticleReq struct {
+	Id       int64    `json:"id"`
+	Title    string   `json:"title"`
+	Content  string   `json:"content"`
+	Format   uint8    `json:"format"`
+	Category string   `json:"category"`
+	Tags     []string `json:"tags"`
+}
+
+func (req ArticleReq) toDomain(uid int64) domain.Article {
+	return domain.Article{
+		Id:       req.Id,
+		Title:    req.Title,
+		Content:  req.Content,
+		Format:   domain.ContentFormat(req.Format),
+		Category: req.Category,
+		Tags:     req.Tags,
+		Author: domain.Author{
+			Id: uid,
+		},
+	}
+}
+
 type ArticleVo struct {
-	Id         int64  `json:"id,omitempty"`
-	Title      string `json:"title,omitempty"`
-	Abstract   string `json:"abstract,omitempty"`
-	Content    string `json:"content,omitempty"`
-	AuthorId   int64  `json:"authorId,omitempty"`
-	AuthorName string `json:"authorName,omitempty"`
-	Status     uint8  `json:"status,omitempty"`
-	Ctime      string `json:"ctime,omitempty"`
-	Utime      string `json:"utime,omitempty"`
+	Id       int64  `json:"id,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Abstract string `json:"abstract,omitempty"`
+	Content  string `json:"content,omitempty"`
+	Format   uint8  `json:"format,omitempty"`
+	// Html is the sanitized rendering of Content
+	Html           string   `json:"html,omitempty"`
+	WordCount      int      `json:"wordCount,omitempty"`
+	ReadingMinutes int      `json:"readingMinutes,omitempty"`
+	AuthorId       int64    `json:"authorId,omitempty"`
+	AuthorName     string   `json:"authorName,omitempty"`
+	Status         uint8    `json:"status,omitempty"`
+	PublishAt      string   `json:"publishAt,omitempty"`
+	Category       string   `json:"category,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	Ctime          string   `json:"ctime,omitempty"`
+	Utime          string   `json:"utime,omitempty"`
+
+	// interactive counters, only filled for readers
+	ReadCnt       int64 `json:"readCnt"`
+	UniqueReadCnt int64 `json:"uniqueReadCnt"`
+	LikeCnt       int64 `json:"likeCnt"`
+	CollectCnt    int64 `json:"collectCnt"`
+	Liked         bool  `json:"liked"`
+	Collected     bool  `json:"collected"`
+	CommentCnt    int64 `json:"commentCnt"`
+}
+
+type HotArticleVo struct {
+	ArticleVo
+	Score float64 `json:"score"`
+}
+
+type TagVo struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+type ArticleRevisionVo struct {
+	Revision int64  `json:"revision"`
+	Title    string `json:"title"`
+	Ctime    string `json:"ctime"`
 }
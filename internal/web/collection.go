@@ -0,0 +1,258 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/service"
+	ijwt "github.com/webook/internal/web/jwt"
+	"github.com/webook/pkg/logger"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var _ Handler = &CollectionHandler{}
+
+type CollectionHandler struct {
+	svc service.CollectionService
+	l   logger.LoggerV1
+}
+
+func NewCollectionHandler(l logger.LoggerV1, svc service.CollectionService) *CollectionHandler {
+	return &CollectionHandler{
+		l:   l,
+		svc: svc,
+	}
+}
+
+func (h *CollectionHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/collections")
+	g.POST("/create", h.Create)
+	g.POST("/edit", h.Edit)
+	g.POST("/delete", h.Delete)
+	// 0 is the default collection of the user
+	g.GET("/:id/articles", h.Articles)
+	server.GET("/users/:id/collections", h.List)
+}
+
+type CollectionVo struct {
+	Id     int64  `json:"id"`
+	Uid    int64  `json:"uid"`
+	Name   string `json:"name"`
+	Public bool   `json:"public"`
+	Ctime  string `json:"ctime"`
+	Utime  string `json:"utime"`
+}
+
+// Create creates a collection and returns its id
+func (h *CollectionHandler) Create(ctx *gin.Context) {
+	type Req struct {
+		Name   string `json:"name"`
+		Public bool   `json:"public"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	id, err := h.svc.Create(ctx, domain.Collection{
+		Uid:    uc.Uid,
+		Name:   req.Name,
+		Public: req.Public,
+	})
+	if !h.handleErr(ctx, err, "Failed to create collection", uc.Uid, 0) {
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: id,
+	})
+}
+
+// Edit renames the collection or changes its visibility
+func (h *CollectionHandler) Edit(ctx *gin.Context) {
+	type Req struct {
+		Id     int64  `json:"id"`
+		Name   string `json:"name"`
+		Public bool   `json:"public"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	err := h.svc.Update(ctx, domain.Collection{
+		Id:     req.Id,
+		Uid:    uc.Uid,
+		Name:   req.Name,
+		Public: req.Public,
+	})
+	if !h.handleErr(ctx, err, "Failed to edit collection", uc.Uid, req.Id) {
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "OK",
+	})
+}
+
+// Delete deletes the collection, the articles in it stay in the default collection
+func (h *CollectionHandler) Delete(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	err := h.svc.Delete(ctx, uc.Uid, req.Id)
+	if !h.handleErr(ctx, err, "Failed to delete collection", uc.Uid, req.Id) {
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "OK",
+	})
+}
+
+// List lists the user's collections, only the public ones for the others
+func (h *CollectionHandler) List(ctx *gin.Context) {
+	uid, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid User Id",
+		})
+		return
+	}
+	var page Page
+	if err = ctx.ShouldBindQuery(&page); err != nil ||
+		page.Limit <= 0 || page.Limit > maxPageSize || page.Offset < 0 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Page Size",
+		})
+		return
+	}
+	// anonymous viewers see the public collections only
+	var uc ijwt.UserClaims
+	if val, ok := ctx.Get("user"); ok {
+		uc, _ = val.(ijwt.UserClaims)
+	}
+	cs, err := h.svc.List(ctx, uid, uc.Uid, page.Offset, page.Limit)
+	if err != nil {
+		h.l.Error("Failed to list collections",
+			logger.Int64("uid", uid),
+			logger.Int("offset", page.Offset),
+			logger.Int("limit", page.Limit),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	res := make([]CollectionVo, 0, len(cs))
+	for _, c := range cs {
+		res = append(res, CollectionVo{
+			Id:     c.Id,
+			Uid:    c.Uid,
+			Name:   c.Name,
+			Public: c.Public,
+			Ctime:  c.CreatedAt.Format(time.DateTime),
+			Utime:  c.UpdatedAt.Format(time.DateTime),
+		})
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+// Articles lists the published articles in the collection, the latest collected first
+func (h *CollectionHandler) Articles(ctx *gin.Context) {
+	cid, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil || cid < 0 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Collection Id",
+		})
+		return
+	}
+	var page Page
+	if err = ctx.ShouldBindQuery(&page); err != nil ||
+		page.Limit <= 0 || page.Limit > maxPageSize || page.Offset < 0 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Page Size",
+		})
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	arts, err := h.svc.Articles(ctx, cid, uc.Uid, page.Offset, page.Limit)
+	if !h.handleErr(ctx, err, "Failed to list collection articles", uc.Uid, cid) {
+		return
+	}
+	res := make([]ArticleVo, 0, len(arts))
+	for _, art := range arts {
+		res = append(res, ArticleVo{
+			Id:       art.Id,
+			Title:    art.Title,
+			Abstract: art.Abstract(),
+			AuthorId: art.Author.Id,
+			Category: art.Category,
+			Tags:     art.Tags,
+			Ctime:    art.CreatedAt.Format(time.DateTime),
+			Utime:    art.UpdatedAt.Format(time.DateTime),
+		})
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+// handleErr writes the response and returns false if err isn't nil
+func (h *CollectionHandler) handleErr(ctx *gin.Context, err error, msg string, uid int64, cid int64) bool {
+	switch err {
+	case nil:
+		return true
+	case service.ErrInvalidCollectionName:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  err.Error(),
+		})
+	case service.ErrDuplicateCollectionName:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Collection Name Exists",
+		})
+	case service.ErrCollectionNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Collection Not Found",
+		})
+	default:
+		h.l.Error(msg,
+			logger.Int64("uid", uid),
+			logger.Int64("cid", cid),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+	}
+	return false
+}
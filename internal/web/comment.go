@@ -0,0 +1,253 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/service"
+	ijwt "github.com/webook/internal/web/jwt"
+	"github.com/webook/pkg/logger"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var _ Handler = &CommentHandler{}
+
+type CommentHandler struct {
+	svc service.CommentService
+	l   logger.LoggerV1
+}
+
+func NewCommentHandler(l logger.LoggerV1, svc service.CommentService) *CommentHandler {
+	return &CommentHandler{
+		l:   l,
+		svc: svc,
+	}
+}
+
+func (h *CommentHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/comments")
+	g.POST("/create", h.Create)
+	g.POST("/delete", h.Delete)
+	g.GET("/article/:aid", h.List)
+	g.GET("/replies/:id", h.ListReplies)
+}
+
+// Create comments on a published article, or replies to another comment
+func (h *CommentHandler) Create(ctx *gin.Context) {
+	var req CommentReq
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	id, err := h.svc.Create(ctx, domain.Comment{
+		ArticleId: req.ArticleId,
+		Commentator: domain.Commentator{
+			Id: uc.Uid,
+		},
+		Content:  req.Content,
+		ParentId: req.ParentId,
+	})
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Data: id,
+		})
+	case service.ErrInvalidComment:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Comment Is Empty Or Too Long",
+		})
+	case service.ErrEmailNotVerified:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Please Verify Your Email Before Commenting",
+		})
+	case service.ErrArticleNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Article Not Found",
+		})
+	case service.ErrCommentNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Replied Comment Not Found",
+		})
+	default:
+		h.l.Error("Failed to create comment",
+			logger.Int64("aid", req.ArticleId),
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+	}
+}
+
+// Delete deletes the comment with its replies, by the commenter or the article author
+func (h *CommentHandler) Delete(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	err := h.svc.Delete(ctx, uc.Uid, req.Id)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "OK",
+		})
+	case service.ErrCommentNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Comment Not Found",
+		})
+	case service.ErrArticleNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Article Not Found",
+		})
+	case service.ErrCommentPermissionDenied:
+		h.l.Warn("Failed to delete comment, neither commenter nor author",
+			logger.Int64("cid", req.Id),
+			logger.Int64("uid", uc.Uid))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Permission Denied",
+		})
+	default:
+		h.l.Error("Failed to delete comment",
+			logger.Int64("cid", req.Id),
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+	}
+}
+
+// List returns the top-level comments of the article, each with its first few replies
+func (h *CommentHandler) List(ctx *gin.Context) {
+	aid, err := strconv.ParseInt(ctx.Param("aid"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Article Id",
+		})
+		return
+	}
+	var page Page
+	if err = ctx.ShouldBindQuery(&page); err != nil ||
+		page.Limit <= 0 || page.Limit > maxPageSize || page.Offset < 0 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Page Size",
+		})
+		return
+	}
+	cs, err := h.svc.List(ctx, aid, page.Offset, page.Limit)
+	if err == service.ErrArticleNotFound {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Article Not Found",
+		})
+		return
+	}
+	if err != nil {
+		h.l.Error("Failed to list comments",
+			logger.Int64("aid", aid),
+			logger.Int("offset", page.Offset),
+			logger.Int("limit", page.Limit),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: h.toVos(cs),
+	})
+}
+
+// ListReplies returns the replies in the thread of the top-level comment
+func (h *CommentHandler) ListReplies(ctx *gin.Context) {
+	rootId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Comment Id",
+		})
+		return
+	}
+	var page Page
+	if err = ctx.ShouldBindQuery(&page); err != nil ||
+		page.Limit <= 0 || page.Limit > maxPageSize || page.Offset < 0 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Page Size",
+		})
+		return
+	}
+	cs, err := h.svc.ListReplies(ctx, rootId, page.Offset, page.Limit)
+	switch err {
+	case service.ErrArticleNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Article Not Found",
+		})
+		return
+	case service.ErrCommentNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Comment Not Found",
+		})
+		return
+	}
+	if err != nil {
+		h.l.Error("Failed to list replies",
+			logger.Int64("cid", rootId),
+			logger.Int("offset", page.Offset),
+			logger.Int("limit", page.Limit),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: h.toVos(cs),
+	})
+}
+
+func (h *CommentHandler) toVos(cs []domain.Comment) []CommentVo {
+	res := make([]CommentVo, 0, len(cs))
+	for _, c := range cs {
+		res = append(res, CommentVo{
+			Id:              c.Id,
+			ArticleId:       c.ArticleId,
+			CommentatorId:   c.Commentator.Id,
+			CommentatorName: c.Commentator.Name,
+			Content:         c.Content,
+			RootId:          c.RootId,
+			ParentId:        c.ParentId,
+			Replies:         h.toVos(c.Replies),
+			Ctime:           c.CreatedAt.Format(time.DateTime),
+		})
+	}
+	return res
+}
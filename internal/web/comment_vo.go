@@ -0,0 +1,20 @@
+package web
+
+type CommentReq struct {
+	ArticleId int64 `json:"articleId"`
+	// ParentId is the comment replied to, 0 for top-level comments
+	ParentId int64  `json:"parentId"`
+	Content  string `json:"content"`
+}
+
+type CommentVo struct {
+	Id              int64       `json:"id"`
+	ArticleId       int64       `json:"articleId"`
+	CommentatorId   int64       `json:"commentatorId"`
+	CommentatorName string      `json:"commentatorName"`
+	Content         string      `json:"content"`
+	RootId          int64       `json:"rootId"`
+	ParentId        int64       `json:"parentId"`
+	Replies         []CommentVo `json:"replies,omitempty"`
+	Ctime           string      `json:"ctime"`
+}
@@ -0,0 +1,103 @@
+package web
+
+import (
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/webook/internal/service"
+	ijwt "github.com/webook/internal/web/jwt"
+	"github.com/webook/pkg/logger"
+	"io"
+	"net/http"
+	"time"
+)
+
+var _ Handler = &EventHandler{}
+
+type EventHandler struct {
+	svc service.UserEventService
+	l   logger.LoggerV1
+	ijwt.Handler
+	// heartbeat keeps the idle streams from being cut by the proxies,
+	// the session is checked again on each heartbeat
+	heartbeat time.Duration
+}
+
+func NewEventHandler(l logger.LoggerV1, svc service.UserEventService, hdl ijwt.Handler) *EventHandler {
+	return &EventHandler{
+		l:         l,
+		svc:       svc,
+		Handler:   hdl,
+		heartbeat: time.Second * 15,
+	}
+}
+
+func (h *EventHandler) RegisterRoutes(server *gin.Engine) {
+	server.GET("/events/stream", h.Stream)
+}
+
+// Stream pushes the user's events as server-sent events.
+// It ends once the session is logged out or the token expires,
+// the client resumes with the Last-Event-ID header after reconnecting
+func (h *EventHandler) Stream(ctx *gin.Context) {
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	lastId := ctx.GetHeader("Last-Event-ID")
+	events, err := h.svc.Subscribe(ctx.Request.Context(), uc.Uid, lastId)
+	if err != nil {
+		h.l.Error("Failed to subscribe user events",
+			logger.Int64("uid", uc.Uid),
+			logger.String("lastId", lastId),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	// stop nginx from buffering the stream
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case evt, ok := <-events:
+			if !ok {
+				// fell behind, the client reconnects and resumes
+				return false
+			}
+			ctx.Render(-1, sse.Event{
+				Id:    evt.Id,
+				Event: evt.Type,
+				Data:  evt.Data,
+			})
+			return true
+		case <-ticker.C:
+			if !h.sessionValid(ctx, uc) {
+				return false
+			}
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		}
+	})
+}
+
+// sessionValid tells whether the stream can go on, the token checked when it opened may be revoked since
+func (h *EventHandler) sessionValid(ctx *gin.Context, uc ijwt.UserClaims) bool {
+	if uc.ExpiresAt != nil && time.Now().After(uc.ExpiresAt.Time) {
+		return false
+	}
+	err := h.CheckSession(ctx, uc.Uid, uc.Ssid, ijwt.IssuedAt(uc.RegisteredClaims))
+	return err == nil
+}
@@ -0,0 +1,98 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/webook/internal/service"
+	ijwt "github.com/webook/internal/web/jwt"
+	"github.com/webook/pkg/logger"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var _ Handler = &FeedHandler{}
+
+type FeedHandler struct {
+	svc service.FeedService
+	l   logger.LoggerV1
+}
+
+func NewFeedHandler(l logger.LoggerV1, svc service.FeedService) *FeedHandler {
+	return &FeedHandler{
+		l:   l,
+		svc: svc,
+	}
+}
+
+func (h *FeedHandler) RegisterRoutes(server *gin.Engine) {
+	server.GET("/feed", h.Feed)
+}
+
+type FeedVo struct {
+	Articles []ArticleVo `json:"articles"`
+	// Cursor is passed back to get the next page, 0 means no more
+	Cursor int64 `json:"cursor"`
+}
+
+// Feed returns the new articles of the authors the user follows, the newest first.
+// It pages by cursor, which is the millisecond timestamp returned by the previous page
+func (h *FeedHandler) Feed(ctx *gin.Context) {
+	cursor, err := strconv.ParseInt(ctx.DefaultQuery("cursor", "0"), 10, 64)
+	if err != nil || cursor < 0 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Cursor",
+		})
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > maxPageSize {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Page Size",
+		})
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	before := time.Now()
+	if cursor > 0 {
+		before = time.UnixMilli(cursor)
+	}
+	arts, next, err := h.svc.Feed(ctx, uc.Uid, before, limit)
+	if err != nil {
+		h.l.Error("Failed to get feed",
+			logger.Int64("uid", uc.Uid),
+			logger.Int64("cursor", cursor),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	res := FeedVo{
+		Articles: make([]ArticleVo, 0, len(arts)),
+	}
+	if !next.IsZero() {
+		res.Cursor = next.UnixMilli()
+	}
+	for _, art := range arts {
+		res.Articles = append(res.Articles, ArticleVo{
+			Id:       art.Id,
+			Title:    art.Title,
+			Abstract: art.Abstract(),
+			AuthorId: art.Author.Id,
+			Category: art.Category,
+			Tags:     art.Tags,
+			Ctime:    art.CreatedAt.Format(time.DateTime),
+			Utime:    art.UpdatedAt.Format(time.DateTime),
+		})
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
@@ -0,0 +1,168 @@
+package web
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/service"
+	ijwt "github.com/webook/internal/web/jwt"
+	"github.com/webook/pkg/logger"
+	"net/http"
+	"strconv"
+)
+
+var _ Handler = &FollowHandler{}
+
+type FollowHandler struct {
+	svc service.FollowService
+	l   logger.LoggerV1
+}
+
+func NewFollowHandler(l logger.LoggerV1, svc service.FollowService) *FollowHandler {
+	return &FollowHandler{
+		l:   l,
+		svc: svc,
+	}
+}
+
+func (h *FollowHandler) RegisterRoutes(server *gin.Engine) {
+	ug := server.Group("/users")
+	ug.POST("/follow", h.Follow)
+	ug.POST("/unfollow", h.CancelFollow)
+	ug.GET("/:id/followers", h.Followers)
+	ug.GET("/:id/followees", h.Followees)
+}
+
+type UserVo struct {
+	Id       int64  `json:"id"`
+	Nickname string `json:"nickname"`
+	AboutMe  string `json:"aboutMe"`
+}
+
+// Follow follows the user, following twice is a no-op
+func (h *FollowHandler) Follow(ctx *gin.Context) {
+	type Req struct {
+		Followee int64 `json:"followee"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	err := h.svc.Follow(ctx, uc.Uid, req.Followee)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "OK",
+		})
+	case service.ErrFollowSelf:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Can't Follow Yourself",
+		})
+	case service.ErrUserNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "User Not Found",
+		})
+	default:
+		h.l.Error("Failed to follow",
+			logger.Int64("follower", uc.Uid),
+			logger.Int64("followee", req.Followee),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+	}
+}
+
+// CancelFollow unfollows the user, unfollowing someone not followed is a no-op
+func (h *FollowHandler) CancelFollow(ctx *gin.Context) {
+	type Req struct {
+		Followee int64 `json:"followee"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	err := h.svc.CancelFollow(ctx, uc.Uid, req.Followee)
+	if err != nil {
+		h.l.Error("Failed to unfollow",
+			logger.Int64("follower", uc.Uid),
+			logger.Int64("followee", req.Followee),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "OK",
+	})
+}
+
+// Followers lists who follows the user
+func (h *FollowHandler) Followers(ctx *gin.Context) {
+	h.list(ctx, "followers", h.svc.Followers)
+}
+
+// Followees lists who the user follows
+func (h *FollowHandler) Followees(ctx *gin.Context) {
+	h.list(ctx, "followees", h.svc.Followees)
+}
+
+func (h *FollowHandler) list(ctx *gin.Context, name string,
+	find func(ctx context.Context, uid int64, offset int, limit int) ([]domain.User, error)) {
+	uid, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid User Id",
+		})
+		return
+	}
+	var page Page
+	if err = ctx.ShouldBindQuery(&page); err != nil ||
+		page.Limit <= 0 || page.Limit > maxPageSize || page.Offset < 0 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Page Size",
+		})
+		return
+	}
+	users, err := find(ctx, uid, page.Offset, page.Limit)
+	if err != nil {
+		h.l.Error("Failed to list "+name,
+			logger.Int64("uid", uid),
+			logger.Int("offset", page.Offset),
+			logger.Int("limit", page.Limit),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	res := make([]UserVo, 0, len(users))
+	for _, u := range users {
+		res = append(res, UserVo{
+			Id:       u.Id,
+			Nickname: u.Nickname,
+			AboutMe:  u.AboutMe,
+		})
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
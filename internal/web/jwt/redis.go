@@ -7,30 +7,58 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"github.com/webook/internal/domain"
+	"github.com/webook/pkg/eventbus"
+	"go.uber.org/zap"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 type RedisJWTHandler struct {
 	client        redis.Cmdable
+	bus           eventbus.Bus
 	signingMethod jwt.SigningMethod
 	rcExpiration  time.Duration
 }
 
-func NewRedisJWTHandler(client redis.Cmdable) Handler {
+func NewRedisJWTHandler(client redis.Cmdable, bus eventbus.Bus) Handler {
 	return &RedisJWTHandler{
 		client:        client,
+		bus:           bus,
 		signingMethod: jwt.SigningMethodHS512,
 		rcExpiration:  time.Hour * 24 * 7,
 	}
 }
 
-func (h *RedisJWTHandler) CheckSession(ctx *gin.Context, ssid string) error {
-	cnt, err := h.client.Exists(ctx, fmt.Sprintf("users:ssid:%s", ssid)).Result()
+func (h *RedisJWTHandler) CheckSession(ctx *gin.Context, uid int64, ssid string, issuedAt time.Time) error {
+	pipe := h.client.Pipeline()
+	exists := pipe.Exists(ctx, fmt.Sprintf("users:ssid:%s", ssid))
+	revoked := pipe.HMGet(ctx, h.revokedKey(uid), "before", "keep")
+	_, err := pipe.Exec(ctx)
 	if err != nil {
 		return err
 	}
-	if cnt > 0 {
+	if exists.Val() > 0 {
+		return errors.New("Invalid Token")
+	}
+	vals := revoked.Val()
+	before, ok := vals[0].(string)
+	if !ok {
+		// never cleared
+		return nil
+	}
+	if keep, _ := vals[1].(string); keep != "" && keep == ssid {
+		return nil
+	}
+	sec, err := strconv.ParseInt(before, 10, 64)
+	if err != nil {
+		return err
+	}
+	// IssuedAt is in seconds, the tokens issued in the same second are let go rather than rejecting the new logins.
+	// The tokens issued before the sessions were tracked have no IssuedAt, they are revoked as well
+	if issuedAt.Unix() < sec {
 		return errors.New("Invalid Token")
 	}
 	return nil
@@ -58,7 +86,40 @@ func (h *RedisJWTHandler) SetLoginToken(ctx *gin.Context, uid int64) error {
 	if err != nil {
 		return err
 	}
-	return h.SetJWTToken(ctx, uid, ssid)
+	err = h.addSession(ctx, uid, ssid)
+	if err != nil {
+		return err
+	}
+	err = h.SetJWTToken(ctx, uid, ssid)
+	if err != nil {
+		return err
+	}
+	h.notifyLogin(ctx, uid)
+	return nil
+}
+
+// notifyLogin tells the user someone logged in, so that they can spot a stolen password
+func (h *RedisJWTHandler) notifyLogin(ctx *gin.Context, uid int64) {
+	err := h.bus.Publish(ctx, domain.TopicNotification, domain.NotificationEvent{
+		Uid:     uid,
+		Kind:    domain.NotificationKindLogin,
+		Content: fmt.Sprintf("Logged in from %s with %s", ctx.ClientIP(), truncate(ctx.GetHeader("User-Agent"), maxUserAgentLength)),
+		Time:    time.Now(),
+	})
+	if err != nil {
+		// the user is logged in anyway
+		zap.L().Error("Failed to notify login", zap.Int64("uid", uid), zap.Error(err))
+	}
+}
+
+// maxUserAgentLength is in runes, the content column fits 1024 only
+const maxUserAgentLength = 256
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "..."
 }
 
 func (h *RedisJWTHandler) ClearToken(ctx *gin.Context) error {
@@ -69,12 +130,57 @@ func (h *RedisJWTHandler) ClearToken(ctx *gin.Context) error {
 	return h.client.Set(ctx, fmt.Sprintf("users:ssid:%s", uc.Ssid), "", h.rcExpiration).Err()
 }
 
+// addSession keeps the ssids of uid, so that they can be cleared together.
+// The set lives as long as the latest refresh token
+func (h *RedisJWTHandler) addSession(ctx *gin.Context, uid int64, ssid string) error {
+	key := h.sessionsKey(uid)
+	pipe := h.client.TxPipeline()
+	pipe.SAdd(ctx, key, ssid)
+	pipe.Expire(ctx, key, h.rcExpiration)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// ClearSessions marks the tracked sessions logged out, and revokes every token of uid issued before now,
+// which covers the sessions created before they were tracked
+func (h *RedisJWTHandler) ClearSessions(ctx *gin.Context, uid int64, keepSsid string) error {
+	key := h.sessionsKey(uid)
+	ssids, err := h.client.SMembers(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	pipe := h.client.TxPipeline()
+	revokedKey := h.revokedKey(uid)
+	pipe.HSet(ctx, revokedKey, "before", time.Now().Unix(), "keep", keepSsid)
+	// the tokens issued before expire by then
+	pipe.Expire(ctx, revokedKey, h.rcExpiration)
+	for _, ssid := range ssids {
+		if ssid == keepSsid {
+			continue
+		}
+		// the same as logging out
+		pipe.Set(ctx, fmt.Sprintf("users:ssid:%s", ssid), "", h.rcExpiration)
+		pipe.SRem(ctx, key, ssid)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (h *RedisJWTHandler) sessionsKey(uid int64) string {
+	return fmt.Sprintf("users:sessions:%d", uid)
+}
+
+func (h *RedisJWTHandler) revokedKey(uid int64) string {
+	return fmt.Sprintf("users:revoked:%d", uid)
+}
+
 func (h *RedisJWTHandler) SetJWTToken(ctx *gin.Context, uid int64, ssid string) error {
 	uc := UserClaims{
 		Uid:       uid,
 		Ssid:      ssid,
 		UserAgent: ctx.GetHeader("User-Agent"),
 		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt: jwt.NewNumericDate(time.Now()),
 			// expire after 1 minute, use 30 min for testing
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute * 30)),
 		},
@@ -94,6 +200,7 @@ func (h *RedisJWTHandler) setRefreshToken(ctx *gin.Context, uid int64, ssid stri
 		Uid:  uid,
 		Ssid: ssid,
 		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(h.rcExpiration)),
 		},
 	}
@@ -121,3 +228,11 @@ type RefreshClaims struct {
 	Uid  int64
 	Ssid string
 }
+
+// IssuedAt is zero for the tokens without IssuedAt
+func IssuedAt(claims jwt.RegisteredClaims) time.Time {
+	if claims.IssuedAt == nil {
+		return time.Time{}
+	}
+	return claims.IssuedAt.Time
+}
@@ -1,11 +1,17 @@
 package jwt
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/gin-gonic/gin"
+	"time"
+)
 
 type Handler interface {
 	ClearToken(ctx *gin.Context) error
 	ExtractToken(ctx *gin.Context) string
 	SetLoginToken(ctx *gin.Context, uid int64) error
 	SetJWTToken(ctx *gin.Context, uid int64, ssid string) error
-	CheckSession(ctx *gin.Context, ssid string) error
+	// CheckSession rejects the sessions logged out, and the tokens issued before ClearSessions of uid
+	CheckSession(ctx *gin.Context, uid int64, ssid string, issuedAt time.Time) error
+	// ClearSessions logs uid out everywhere except the session keepSsid, which can be empty
+	ClearSessions(ctx *gin.Context, uid int64, keepSsid string) error
 }
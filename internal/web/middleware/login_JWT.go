@@ -5,6 +5,7 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	ijwt "github.com/webook/internal/web/jwt"
 	"net/http"
+	"strings"
 )
 
 type LoginJWTMiddlewareBuilder struct {
@@ -24,17 +25,23 @@ func (m *LoginJWTMiddlewareBuilder) CheckLogin() gin.HandlerFunc {
 			path == "/users/login" ||
 			path == "/users/login_sms/code/send" ||
 			path == "/users/login_sms" ||
-			path == "/oauth2/wechat/authurl"||
+			path == "/users/password/reset/send" ||
+			path == "/users/password/reset" ||
+			path == "/oauth2/wechat/authurl" ||
 			path == "/oauth2/wechat/callback" {
 			// No need to check validation
 			return
 		}
 
 		tokenStr := m.ExtractToken(ctx)
+		if tokenStr == "" && ctx.Request.Method == http.MethodGet && m.openToAnonymous(path) {
+			// the logged-in readers still go through the check below
+			return
+		}
 		var uc ijwt.UserClaims
 		token, err := jwt.ParseWithClaims(tokenStr, &uc, func(token *jwt.Token) (interface{}, error) {
 			return ijwt.JWTKey, nil
-		}
+		})
 		if err != nil {
 			//println("Incorrect token")
 			ctx.AbortWithStatus(http.StatusUnauthorized)
@@ -49,7 +56,7 @@ func (m *LoginJWTMiddlewareBuilder) CheckLogin() gin.HandlerFunc {
 			return
 		}
 
-		err = m.CheckSession(ctx, uc.Ssid)
+		err = m.CheckSession(ctx, uc.Uid, uc.Ssid, ijwt.IssuedAt(uc.RegisteredClaims))
 		if err != nil {
 			// invaid token or redis issue
 			ctx.AbortWithStatus(http.StatusUnauthorized)
@@ -66,3 +73,17 @@ func (m *LoginJWTMiddlewareBuilder) CheckLogin() gin.HandlerFunc {
 		ctx.Set("user", uc)
 	}
 }
+
+// openToAnonymous tells whether anonymous readers can GET path,
+// which are published articles, their comments, the tags, search, the hot list,
+// the users' collections and the syndication feeds
+func (m *LoginJWTMiddlewareBuilder) openToAnonymous(path string) bool {
+	return strings.HasPrefix(path, "/pub/") ||
+		path == "/articles/hot" ||
+		strings.HasPrefix(path, "/tags/") ||
+		strings.HasPrefix(path, "/comments/") ||
+		path == "/search" ||
+		path == "/feed.rss" ||
+		(strings.HasPrefix(path, "/users/") && strings.HasSuffix(path, "/feed.atom")) ||
+		(strings.HasPrefix(path, "/users/") && strings.HasSuffix(path, "/collections"))
+}
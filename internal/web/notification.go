@@ -0,0 +1,180 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/webook/internal/service"
+	ijwt "github.com/webook/internal/web/jwt"
+	"github.com/webook/pkg/logger"
+	"net/http"
+	"time"
+)
+
+var _ Handler = &NotificationHandler{}
+
+type NotificationHandler struct {
+	svc service.NotificationService
+	l   logger.LoggerV1
+}
+
+func NewNotificationHandler(l logger.LoggerV1, svc service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		l:   l,
+		svc: svc,
+	}
+}
+
+func (h *NotificationHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/notifications")
+	g.GET("/list", h.List)
+	g.GET("/unread_count", h.UnreadCount)
+	g.POST("/read", h.MarkRead)
+	g.POST("/read_all", h.MarkAllRead)
+}
+
+type NotificationVo struct {
+	Id      int64  `json:"id"`
+	Kind    string `json:"kind"`
+	Summary string `json:"summary"`
+	Content string `json:"content"`
+	Count   int64  `json:"count"`
+	Read    bool   `json:"read"`
+	Ctime   string `json:"ctime"`
+	Utime   string `json:"utime"`
+}
+
+type NotificationListVo struct {
+	Unread        int64            `json:"unread"`
+	Notifications []NotificationVo `json:"notifications"`
+}
+
+// List returns the notifications of the logged-in user with the unread count
+func (h *NotificationHandler) List(ctx *gin.Context) {
+	var page Page
+	if err := ctx.ShouldBindQuery(&page); err != nil ||
+		page.Limit <= 0 || page.Limit > maxPageSize || page.Offset < 0 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Page Size",
+		})
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	ns, err := h.svc.List(ctx, uc.Uid, page.Offset, page.Limit)
+	if err != nil {
+		h.l.Error("Failed to list notifications",
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	unread, err := h.svc.UnreadCount(ctx, uc.Uid)
+	if err != nil {
+		h.l.Error("Failed to count unread notifications",
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	res := NotificationListVo{
+		Unread:        unread,
+		Notifications: make([]NotificationVo, 0, len(ns)),
+	}
+	for _, n := range ns {
+		res.Notifications = append(res.Notifications, NotificationVo{
+			Id:      n.Id,
+			Kind:    n.Kind,
+			Summary: n.Summary(),
+			Content: n.Content,
+			Count:   n.Count,
+			Read:    n.Read,
+			Ctime:   n.CreatedAt.Format(time.DateTime),
+			Utime:   n.UpdatedAt.Format(time.DateTime),
+		})
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+// UnreadCount is for the badge, it is much cheaper than List
+func (h *NotificationHandler) UnreadCount(ctx *gin.Context) {
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	unread, err := h.svc.UnreadCount(ctx, uc.Uid)
+	if err != nil {
+		h.l.Error("Failed to count unread notifications",
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: unread,
+	})
+}
+
+func (h *NotificationHandler) MarkRead(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	if err := h.svc.MarkRead(ctx, uc.Uid, req.Id); err != nil {
+		h.l.Error("Failed to mark notification read",
+			logger.Int64("nid", req.Id),
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "OK",
+	})
+}
+
+func (h *NotificationHandler) MarkAllRead(ctx *gin.Context) {
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	if err := h.svc.MarkAllRead(ctx, uc.Uid); err != nil {
+		h.l.Error("Failed to mark all notifications read",
+			logger.Int64("uid", uc.Uid),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "OK",
+	})
+}
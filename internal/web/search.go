@@ -0,0 +1,98 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/webook/internal/service"
+	"github.com/webook/pkg/logger"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var _ Handler = &SearchHandler{}
+
+// maxQueryLength is in runes
+const maxQueryLength = 64
+
+type SearchHandler struct {
+	svc service.SearchService
+	l   logger.LoggerV1
+}
+
+func NewSearchHandler(l logger.LoggerV1, svc service.SearchService) *SearchHandler {
+	return &SearchHandler{
+		l:   l,
+		svc: svc,
+	}
+}
+
+func (h *SearchHandler) RegisterRoutes(server *gin.Engine) {
+	server.GET("/search", h.Search)
+}
+
+type SearchVo struct {
+	Articles []ArticleVo `json:"articles"`
+	Users    []UserVo    `json:"users"`
+}
+
+// Search searches the published articles and the users, the most relevant first.
+// The page applies to articles and users separately
+func (h *SearchHandler) Search(ctx *gin.Context) {
+	q := strings.TrimSpace(ctx.Query("q"))
+	if q == "" || utf8.RuneCountInString(q) > maxQueryLength {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Query",
+		})
+		return
+	}
+	page := Page{Limit: 20}
+	if err := ctx.ShouldBindQuery(&page); err != nil ||
+		page.Limit <= 0 || page.Limit > maxPageSize || page.Offset < 0 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Invalid Page Size",
+		})
+		return
+	}
+	res, err := h.svc.Search(ctx, q, page.Offset, page.Limit)
+	if err != nil {
+		h.l.Error("Failed to search",
+			logger.String("q", q),
+			logger.Int("offset", page.Offset),
+			logger.Int("limit", page.Limit),
+			logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	vo := SearchVo{
+		Articles: make([]ArticleVo, 0, len(res.Articles)),
+		Users:    make([]UserVo, 0, len(res.Users)),
+	}
+	for _, art := range res.Articles {
+		vo.Articles = append(vo.Articles, ArticleVo{
+			Id:       art.Id,
+			Title:    art.Title,
+			Abstract: art.Abstract(),
+			AuthorId: art.Author.Id,
+			Category: art.Category,
+			Tags:     art.Tags,
+			Ctime:    art.CreatedAt.Format(time.DateTime),
+			Utime:    art.UpdatedAt.Format(time.DateTime),
+		})
+	}
+	for _, u := range res.Users {
+		vo.Users = append(vo.Users, UserVo{
+			Id:       u.Id,
+			Nickname: u.Nickname,
+			AboutMe:  u.AboutMe,
+		})
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: vo,
+	})
+}
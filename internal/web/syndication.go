@@ -0,0 +1,215 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/service"
+	"github.com/webook/pkg/logger"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var _ Handler = &SyndicationHandler{}
+
+// SyndicationHandler serves the Atom and RSS feeds for feed readers.
+// Feed readers go by the HTTP status, so the errors are not wrapped in Result
+type SyndicationHandler struct {
+	svc     service.ArticleService
+	userSvc service.UserService
+	l       logger.LoggerV1
+	// siteURL is where the readers open the articles, without the trailing slash
+	siteURL string
+	title   string
+	// maxAge is how long the feed readers and proxies may cache the feeds
+	maxAge time.Duration
+}
+
+func NewSyndicationHandler(l logger.LoggerV1, svc service.ArticleService, userSvc service.UserService,
+	siteURL string, title string) *SyndicationHandler {
+	return &SyndicationHandler{
+		l:       l,
+		svc:     svc,
+		userSvc: userSvc,
+		siteURL: strings.TrimSuffix(siteURL, "/"),
+		title:   title,
+		maxAge:  time.Minute * 5,
+	}
+}
+
+func (h *SyndicationHandler) RegisterRoutes(server *gin.Engine) {
+	server.GET("/users/:id/feed.atom", h.AuthorAtom)
+	server.GET("/feed.rss", h.SiteRSS)
+}
+
+// AuthorAtom is the Atom feed of the author's latest published articles
+func (h *SyndicationHandler) AuthorAtom(ctx *gin.Context) {
+	uid, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	author, err := h.userSvc.FindById(ctx, uid)
+	if err == service.ErrUserNotFound {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.l.Error("Failed to find author of feed", logger.Int64("uid", uid), logger.Error(err))
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	arts, err := h.svc.ListLatestPub(ctx, uid)
+	if err != nil {
+		h.l.Error("Failed to list articles of feed", logger.Int64("uid", uid), logger.Error(err))
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	name := h.authorName(author.Id, author.Nickname)
+	etag := h.etag("atom", name, arts)
+	if h.notModified(ctx, etag) {
+		return
+	}
+
+	self := fmt.Sprintf("%s/users/%d/feed.atom", h.siteURL, uid)
+	profile := fmt.Sprintf("%s/users/%d", h.siteURL, uid)
+	// the feed is updated when its latest entry is, or when the author joined if there are none
+	updated := author.CreatedAt
+	feed := atomFeed{
+		Id:    self,
+		Title: fmt.Sprintf("%s - %s", name, h.title),
+		Links: []atomLink{
+			{Href: self, Rel: "self", Type: "application/atom+xml"},
+			{Href: profile, Rel: "alternate", Type: "text/html"},
+		},
+		Author: atomPerson{
+			Name: name,
+			Uri:  profile,
+		},
+		Entries: make([]atomEntry, 0, len(arts)),
+	}
+	for _, art := range arts {
+		if art.UpdatedAt.After(updated) {
+			updated = art.UpdatedAt
+		}
+		link := h.articleURL(art.Id)
+		entry := atomEntry{
+			Id:        link,
+			Title:     art.Title,
+			Updated:   art.UpdatedAt.UTC().Format(time.RFC3339),
+			Published: art.CreatedAt.UTC().Format(time.RFC3339),
+			Links: []atomLink{
+				{Href: link, Rel: "alternate", Type: "text/html"},
+			},
+			Summary: atomText{Type: "text", Body: art.Abstract()},
+			Content: atomText{Type: "html", Body: art.HTML()},
+		}
+		for _, tag := range art.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+	h.render(ctx, "application/atom+xml; charset=utf-8", etag, feed)
+}
+
+// SiteRSS is the RSS feed of the latest published articles of the whole site
+func (h *SyndicationHandler) SiteRSS(ctx *gin.Context) {
+	arts, err := h.svc.ListLatestPub(ctx, 0)
+	if err != nil {
+		h.l.Error("Failed to list articles of feed", logger.Error(err))
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	etag := h.etag("rss", "", arts)
+	if h.notModified(ctx, etag) {
+		return
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       h.title,
+			Link:        h.siteURL,
+			Description: fmt.Sprintf("The latest articles on %s", h.title),
+			Items:       make([]rssItem, 0, len(arts)),
+		},
+	}
+	var updated time.Time
+	for _, art := range arts {
+		if art.UpdatedAt.After(updated) {
+			updated = art.UpdatedAt
+		}
+		link := h.articleURL(art.Id)
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       art.Title,
+			Link:        link,
+			Guid:        rssGuid{IsPermaLink: true, Value: link},
+			PubDate:     art.CreatedAt.UTC().Format(time.RFC1123Z),
+			Categories:  art.Tags,
+			Description: art.HTML(),
+		})
+	}
+	if !updated.IsZero() {
+		feed.Channel.LastBuildDate = updated.UTC().Format(time.RFC1123Z)
+	}
+	h.render(ctx, "application/rss+xml; charset=utf-8", etag, feed)
+}
+
+func (h *SyndicationHandler) render(ctx *gin.Context, contentType string, etag string, feed any) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	if err := enc.Encode(feed); err != nil {
+		h.l.Error("Failed to encode feed", logger.String("path", ctx.Request.URL.Path), logger.Error(err))
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	h.cacheHeaders(ctx, etag)
+	ctx.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// etag changes whenever the feed does, so it is computed without rendering the feed
+func (h *SyndicationHandler) etag(kind string, author string, arts []domain.Article) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s|%s|%s|%s", kind, h.siteURL, h.title, author)
+	for _, art := range arts {
+		fmt.Fprintf(hash, "|%d:%d:%s", art.Id, art.UpdatedAt.UnixMilli(), art.Author.Name)
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// notModified writes 304 if the reader has the latest feed already
+func (h *SyndicationHandler) notModified(ctx *gin.Context, etag string) bool {
+	for _, tag := range strings.Split(ctx.GetHeader("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			h.cacheHeaders(ctx, etag)
+			ctx.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+func (h *SyndicationHandler) cacheHeaders(ctx *gin.Context, etag string) {
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.maxAge.Seconds())))
+}
+
+func (h *SyndicationHandler) articleURL(id int64) string {
+	return fmt.Sprintf("%s/articles/%d", h.siteURL, id)
+}
+
+func (h *SyndicationHandler) authorName(uid int64, nickname string) string {
+	if nickname == "" {
+		return fmt.Sprintf("User %d", uid)
+	}
+	return nickname
+}
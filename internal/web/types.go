@@ -7,6 +7,6 @@ type Handler interface {
 }
 
 type Page struct {
-	Limit  int
-	Offset int
+	Limit  int `json:"limit" form:"limit"`
+	Offset int `json:"offset" form:"offset"`
 }
@@ -18,6 +18,9 @@ const (
 	emailRegexPattern    = "^\\w+([-+.]\\w+)*@\\w+([-.]\\w+)*\\.\\w+([-.]\\w+)*$"
 	passwordRegexPattern = `^(?=.*[A-Za-z])(?=.*\d)(?=.*[$@$!%*#?&])[A-Za-z\d$@$!%*#?&]{8,}$`
 	bizLogin             = "login"
+	bizResetPassword     = "reset_password"
+	bizVerifyEmail       = "verify_email"
+	bizBindPhone         = "bind_phone"
 )
 
 type UserHandler struct {
@@ -26,17 +29,23 @@ type UserHandler struct {
 	passwordRexExp *regexp.Regexp
 	svc            service.UserService
 	codeSvc        service.CodeService
+	emailCodeSvc   service.EmailCodeService
+	followSvc      service.FollowService
 }
 
 func NewUserHandler(svc service.UserService,
-	//hdl ijwt.Handler,
-	codeSvc service.CodeService) *UserHandler {
+	hdl ijwt.Handler,
+	codeSvc service.CodeService,
+	emailCodeSvc service.EmailCodeService,
+	followSvc service.FollowService) *UserHandler {
 	return &UserHandler{
 		emailRexExp:    regexp.MustCompile(emailRegexPattern, regexp.None),
 		passwordRexExp: regexp.MustCompile(passwordRegexPattern, regexp.None),
 		svc:            svc,
 		codeSvc:        codeSvc,
-		//Handler:        hdl,
+		emailCodeSvc:   emailCodeSvc,
+		followSvc:      followSvc,
+		Handler:        hdl,
 	}
 }
 
@@ -53,6 +62,15 @@ func (h *UserHandler) RegisterRoutes(server *gin.Engine) {
 	// SMS validation
 	ug.POST("/login_sms/code/send", h.SendSMSLoginCode)
 	ug.POST("/login_sms", h.LoginSMS)
+
+	ug.POST("/password", h.ChangePassword)
+	ug.POST("/email/verify/send", h.SendVerifyEmailCode)
+	ug.POST("/email/verify", h.VerifyEmail)
+	ug.POST("/phone/bind/send", h.SendBindPhoneCode)
+	ug.POST("/phone/bind", h.BindPhone)
+	// password reset by email
+	ug.POST("/password/reset/send", h.SendResetPasswordCode)
+	ug.POST("/password/reset", h.ResetPassword)
 }
 
 func (h *UserHandler) SignUp(ctx *gin.Context) {
@@ -98,6 +116,10 @@ func (h *UserHandler) SignUp(ctx *gin.Context) {
 	})
 	switch err {
 	case nil:
+		// the user can ask for another code if this one is lost
+		if err = h.emailCodeSvc.Send(ctx, bizVerifyEmail, req.Email); err != nil {
+			zap.L().Error("Failed to send email verification code", zap.Error(err))
+		}
 		ctx.String(http.StatusOK, "Signup Complete")
 	case service.ErrDuplicateEmail:
 		ctx.String(http.StatusOK, "Email Already Exists")
@@ -304,6 +326,389 @@ func (h *UserHandler) SendSMSLoginCode(ctx *gin.Context) {
 	}
 }
 
+// SendVerifyEmailCode sends the email verification code again, at most once a minute
+func (h *UserHandler) SendVerifyEmailCode(ctx *gin.Context) {
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	u, ok := h.unverifiedUser(ctx, uc.Uid)
+	if !ok {
+		return
+	}
+	err := h.emailCodeSvc.Send(ctx, bizVerifyEmail, u.Email)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "Code Sent",
+		})
+	case service.ErrCodeSendTooMany:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Code Sent Too Frequent, Please Try Again In A Later Time",
+		})
+	default:
+		zap.L().Error("Failed to send email verification code", zap.Int64("uid", uc.Uid), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+	}
+}
+
+// VerifyEmail marks the email verified with the code sent to it
+func (h *UserHandler) VerifyEmail(ctx *gin.Context) {
+	type Req struct {
+		Code string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	u, ok := h.unverifiedUser(ctx, uc.Uid)
+	if !ok {
+		return
+	}
+	ok, err := h.emailCodeSvc.Verify(ctx, bizVerifyEmail, u.Email, req.Code)
+	if err != nil {
+		zap.L().Error("Email Verification Code Validation Failed", zap.Int64("uid", uc.Uid), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	if !ok {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Code is incorrect, please enter again",
+		})
+		return
+	}
+	if err = h.svc.MarkEmailVerified(ctx, uc.Uid); err != nil {
+		zap.L().Error("Failed to mark email verified", zap.Int64("uid", uc.Uid), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "Email Verified",
+	})
+}
+
+// unverifiedUser writes the response and returns false unless the user has an email to verify
+func (h *UserHandler) unverifiedUser(ctx *gin.Context, uid int64) (domain.User, bool) {
+	u, err := h.svc.FindById(ctx, uid)
+	if err != nil {
+		zap.L().Error("Failed to find user", zap.Int64("uid", uid), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return domain.User{}, false
+	}
+	if !u.EmailUnverified() {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "No Email To Verify",
+		})
+		return domain.User{}, false
+	}
+	return u, true
+}
+
+// SendBindPhoneCode sends the SMS code to prove owning the phone to bind
+func (h *UserHandler) SendBindPhoneCode(ctx *gin.Context) {
+	type Req struct {
+		Phone string `json:"phone"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if req.Phone == "" {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Please Enter Phone Number",
+		})
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	// don't pay for the SMS if the phone can't be bound anyway, BindPhone checks it again
+	owner, err := h.svc.FindByPhone(ctx, req.Phone)
+	switch {
+	case err == nil && owner.Id != uc.Uid:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "This Phone Is Bound To Another Account",
+		})
+		return
+	case err != nil && err != service.ErrUserNotFound:
+		zap.L().Error("Failed to find user by phone", zap.Int64("uid", uc.Uid), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	err = h.codeSvc.Send(ctx, bizBindPhone, req.Phone)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "Code Sent",
+		})
+	case service.ErrCodeSendTooMany:
+		zap.L().Warn("Bind Phone Code Sent Too Frequently")
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Code Sent Too Frequent, Please Try Again In A Later Time",
+		})
+	default:
+		zap.L().Error("Failed to send bind phone code", zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+	}
+}
+
+// BindPhone binds the phone to the current account with the SMS code,
+// so that logging in by SMS finds this account instead of creating another
+func (h *UserHandler) BindPhone(ctx *gin.Context) {
+	type Req struct {
+		Phone string `json:"phone"`
+		Code  string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	ok, err := h.codeSvc.Verify(ctx, bizBindPhone, req.Phone, req.Code)
+	if err != nil {
+		zap.L().Error("Bind Phone Code Validation Failed", zap.Int64("uid", uc.Uid), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	if !ok {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Code is incorrect, please enter again",
+		})
+		return
+	}
+	err = h.svc.BindPhone(ctx, uc.Uid, req.Phone)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "Phone Bound",
+		})
+	case service.ErrPhoneBoundToOther:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "This Phone Is Bound To Another Account",
+		})
+	default:
+		zap.L().Error("Failed to bind phone", zap.Int64("uid", uc.Uid), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+	}
+}
+
+// ChangePassword sets a new password after checking the old one,
+// the other sessions are logged out while the current one stays
+func (h *UserHandler) ChangePassword(ctx *gin.Context) {
+	type Req struct {
+		OldPassword     string `json:"oldPassword"`
+		Password        string `json:"password"`
+		ConfirmPassword string `json:"confirmPassword"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	if req.Password != req.ConfirmPassword {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Inconsistent Password",
+		})
+		return
+	}
+	isPassword, err := h.passwordRexExp.MatchString(req.Password)
+	if err != nil || !isPassword {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Password must contain alphabets, numbers, special characters, and minimum 8 characters.",
+		})
+		return
+	}
+	err = h.svc.ChangePassword(ctx, uc.Uid, req.OldPassword, req.Password)
+	switch err {
+	case nil:
+	case service.ErrIncorrectPassword:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Incorrect Old Password",
+		})
+		return
+	default:
+		zap.L().Error("Failed to change password", zap.Int64("uid", uc.Uid), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	if err = h.ClearSessions(ctx, uc.Uid, uc.Ssid); err != nil {
+		zap.L().Error("Failed to clear sessions", zap.Int64("uid", uc.Uid), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "Password Changed",
+	})
+}
+
+// SendResetPasswordCode emails a code to reset the password,
+// it says the code is sent even if nobody signed up with the email, not to expose who did
+func (h *UserHandler) SendResetPasswordCode(ctx *gin.Context) {
+	type Req struct {
+		Email string `json:"email"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	isEmail, err := h.emailRexExp.MatchString(req.Email)
+	if err != nil || !isEmail {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Incorrect Email Format",
+		})
+		return
+	}
+	_, err = h.svc.FindByEmail(ctx, req.Email)
+	switch err {
+	case nil:
+		err = h.emailCodeSvc.Send(ctx, bizResetPassword, req.Email)
+	case service.ErrUserNotFound:
+		err = nil
+	}
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "Code Sent",
+		})
+	case service.ErrCodeSendTooMany:
+		// the same as the unknown emails, otherwise sending twice tells whether the email has an account
+		zap.L().Warn("Reset Password Code Sent Too Frequently")
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "Code Sent",
+		})
+	default:
+		zap.L().Error("Failed to send reset password code", zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+	}
+}
+
+// ResetPassword sets a new password with the emailed code, and logs the user out everywhere
+func (h *UserHandler) ResetPassword(ctx *gin.Context) {
+	type Req struct {
+		Email           string `json:"email"`
+		Code            string `json:"code"`
+		Password        string `json:"password"`
+		ConfirmPassword string `json:"confirmPassword"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if req.Password != req.ConfirmPassword {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Inconsistent Password",
+		})
+		return
+	}
+	isPassword, err := h.passwordRexExp.MatchString(req.Password)
+	if err != nil || !isPassword {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Password must contain alphabets, numbers, special characters, and minimum 8 characters.",
+		})
+		return
+	}
+	ok, err := h.emailCodeSvc.Verify(ctx, bizResetPassword, req.Email, req.Code)
+	if err != nil {
+		zap.L().Error("Reset Password Code Validation Failed", zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	if !ok {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "Code is incorrect, please enter again",
+		})
+		return
+	}
+	u, err := h.svc.ResetPassword(ctx, req.Email, req.Password)
+	if err != nil {
+		zap.L().Error("Failed to reset password", zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	// whoever knew the old password is logged out
+	if err = h.ClearSessions(ctx, u.Id, ""); err != nil {
+		zap.L().Error("Failed to clear sessions", zap.Int64("uid", u.Id), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "System Error",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "Password Reset, Please Login Again",
+	})
+}
+
 func (h *UserHandler) Profile(ctx *gin.Context) {
 	//sess := sessions.Default(ctx)
 	//uid, ok := sess.Get("userId").(int64)
@@ -319,18 +724,31 @@ func (h *UserHandler) Profile(ctx *gin.Context) {
 	user, err := h.svc.FindById(ctx, uc.Uid)
 	if err != nil {
 		ctx.String(http.StatusOK, "Fail to retrieve user information")
+		return
+	}
+	statistics, err := h.followSvc.Statistics(ctx, uc.Uid)
+	if err != nil {
+		ctx.String(http.StatusOK, "Fail to retrieve user information")
+		return
 	}
 	type User struct {
 		Nickname string `json:"nickname"`
 		Email    string `json:"email"`
-		AboutMe  string `json:"aboutMe"`
-		Birthday string `json:"birthday"`
+		// EmailVerified is always false for the users without email
+		EmailVerified bool   `json:"emailVerified"`
+		AboutMe       string `json:"aboutMe"`
+		Birthday      string `json:"birthday"`
+		Followers     int64  `json:"followers"`
+		Followees     int64  `json:"followees"`
 	}
 	ctx.JSON(http.StatusOK, User{
-		Nickname: user.Nickname,
-		Email:    user.Email,
-		AboutMe:  user.AboutMe,
-		Birthday: user.Birthday.Format(time.DateOnly),
+		Nickname:      user.Nickname,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		AboutMe:       user.AboutMe,
+		Birthday:      user.Birthday.Format(time.DateOnly),
+		Followers:     statistics.Followers,
+		Followees:     statistics.Followees,
 	})
 }
 
@@ -350,7 +768,7 @@ func (h *UserHandler) RefreshToken(ctx *gin.Context) {
 		return
 	}
 
-	err = h.CheckSession(ctx, rc.Ssid)
+	err = h.CheckSession(ctx, rc.Uid, rc.Ssid, ijwt.IssuedAt(rc.RegisteredClaims))
 	if err != nil {
 		// invalid token, or redis issue
 		ctx.AbortWithStatus(http.StatusUnauthorized)
@@ -5,10 +5,13 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	uuid "github.com/lithammer/shortuuid/v4"
+	"github.com/webook/internal/domain"
 	"github.com/webook/internal/service"
 	"github.com/webook/internal/service/oauth2/wechat"
 	ijwt "github.com/webook/internal/web/jwt"
+	"go.uber.org/zap"
 	"net/http"
+	"time"
 )
 
 type OAuth2WechatHandler struct {
@@ -30,12 +33,35 @@ func NewOAuth2WechatHandler(svc wechat.Service, hdl ijwt.Handler, userSvc servic
 }
 
 func (o *OAuth2WechatHandler) RegisterRoutes(server *gin.Engine) {
-	g := server.Group("/oahth2/wechat")
+	g := server.Group("/oauth2/wechat")
 	g.GET("/authurl", o.Auth2URL)
 	g.Any("/callback", o.Callback)
+	// the logged-in users link Wechat to their accounts
+	g.GET("/bind/authurl", o.BindAuth2URL)
+	g.POST("/unlink", o.Unlink)
 }
 
 func (o *OAuth2WechatHandler) Auth2URL(ctx *gin.Context) {
+	o.authURL(ctx, StateClaims{})
+}
+
+// BindAuth2URL starts the flow in bind mode,
+// Callback links Wechat to the current account instead of logging in
+func (o *OAuth2WechatHandler) BindAuth2URL(ctx *gin.Context) {
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	o.authURL(ctx, StateClaims{
+		Uid:           uc.Uid,
+		Ssid:          uc.Ssid,
+		TokenIssuedAt: ijwt.IssuedAt(uc.RegisteredClaims),
+	})
+}
+
+// authURL carries the session in bind mode, the empty claims mean logging in
+func (o *OAuth2WechatHandler) authURL(ctx *gin.Context, sc StateClaims) {
 	state := uuid.New()
 	val, err := o.svc.AuthURL(ctx, state)
 	if err != nil {
@@ -45,12 +71,14 @@ func (o *OAuth2WechatHandler) Auth2URL(ctx *gin.Context) {
 		})
 		return
 	}
-	err = o.setStateCookie(ctx, state)
+	sc.State = state
+	err = o.setStateCookie(ctx, sc)
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{
 			Msg:  "Server Error",
 			Code: 5,
 		})
+		return
 	}
 	ctx.JSON(http.StatusOK, Result{
 		Data: val,
@@ -58,7 +86,9 @@ func (o *OAuth2WechatHandler) Auth2URL(ctx *gin.Context) {
 }
 
 func (o *OAuth2WechatHandler) Callback(ctx *gin.Context) {
-	err := o.verifyState(ctx)
+	sc, err := o.verifyState(ctx)
+	// the state is used once
+	o.clearStateCookie(ctx)
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{
 			Msg:  "illegal request",
@@ -76,6 +106,10 @@ func (o *OAuth2WechatHandler) Callback(ctx *gin.Context) {
 		})
 		return
 	}
+	if sc.Uid > 0 {
+		o.bind(ctx, sc, wechatInfo)
+		return
+	}
 	u, err := o.userSvc.FindOrCreateByWechat(ctx, wechatInfo)
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{
@@ -95,30 +129,89 @@ func (o *OAuth2WechatHandler) Callback(ctx *gin.Context) {
 	return
 }
 
-func (o *OAuth2WechatHandler) verifyState(ctx *gin.Context) error {
+func (o *OAuth2WechatHandler) bind(ctx *gin.Context, sc StateClaims, wechatInfo domain.WechatInfo) {
+	uid := sc.Uid
+	// the user may have logged out since the flow started
+	err := o.CheckSession(ctx, uid, sc.Ssid, sc.TokenIssuedAt)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	err = o.userSvc.BindWechat(ctx, uid, wechatInfo)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "Wechat Linked",
+		})
+	case service.ErrWechatBoundToOther:
+		ctx.JSON(http.StatusOK, Result{
+			Msg:  "This Wechat Is Linked To Another Account",
+			Code: 4,
+		})
+	default:
+		zap.L().Error("Failed to link wechat", zap.Int64("uid", uid), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Msg:  "System Error",
+			Code: 5,
+		})
+	}
+}
+
+// Unlink refuses if the user can't log in without Wechat
+func (o *OAuth2WechatHandler) Unlink(ctx *gin.Context) {
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	err := o.userSvc.UnlinkWechat(ctx, uc.Uid)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "Wechat Unlinked",
+		})
+	case service.ErrWechatNotLinked:
+		ctx.JSON(http.StatusOK, Result{
+			Msg:  "Wechat Is Not Linked",
+			Code: 4,
+		})
+	case service.ErrWechatOnlyLogin:
+		ctx.JSON(http.StatusOK, Result{
+			Msg:  "Please Bind A Phone Or Set An Email And Password Before Unlinking Wechat",
+			Code: 4,
+		})
+	default:
+		zap.L().Error("Failed to unlink wechat", zap.Int64("uid", uc.Uid), zap.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Msg:  "System Error",
+			Code: 5,
+		})
+	}
+}
+
+func (o *OAuth2WechatHandler) verifyState(ctx *gin.Context) (StateClaims, error) {
 	state := ctx.Query("state")
 	ck, err := ctx.Cookie(o.stateCookieName)
 	if err != nil {
-		return fmt.Errorf("Not able to obtain cookie %w", err)
+		return StateClaims{}, fmt.Errorf("Not able to obtain cookie %w", err)
 	}
 	var sc StateClaims
 	_, err = jwt.ParseWithClaims(ck, &sc, func(token *jwt.Token) (interface{}, error) {
 		return o.key, nil
 	})
 	if err != nil {
-		return fmt.Errorf("Failed to decrypt token %w", err)
+		return StateClaims{}, fmt.Errorf("Failed to decrypt token %w", err)
 	}
 	if state != sc.State {
 		// state not matching
-		return fmt.Errorf("state not matching")
+		return StateClaims{}, fmt.Errorf("state not matching")
 	}
-	return nil
+	return sc, nil
 }
 
-func (o *OAuth2WechatHandler) setStateCookie(ctx *gin.Context, state string) error {
-	claims := StateClaims{
-		State: state,
-	}
+func (o *OAuth2WechatHandler) setStateCookie(ctx *gin.Context, claims StateClaims) error {
+	// as long as the cookie
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Minute * 10))
 	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
 	tokenStr, err := token.SignedString(o.key)
 	if err != nil {
@@ -128,7 +221,16 @@ func (o *OAuth2WechatHandler) setStateCookie(ctx *gin.Context, state string) err
 	return nil
 }
 
+func (o *OAuth2WechatHandler) clearStateCookie(ctx *gin.Context) {
+	ctx.SetCookie(o.stateCookieName, "", -1, "/oauth2/wechat/callback", "", false, true)
+}
+
 type StateClaims struct {
 	jwt.RegisteredClaims
 	State string
+	// Uid is the account to link Wechat to in bind mode, 0 means logging in
+	Uid int64
+	// Ssid and TokenIssuedAt are of the session starting the bind, it must be still valid on callback
+	Ssid          string
+	TokenIssuedAt time.Time
 }
@@ -0,0 +1,22 @@
+package ioc
+
+import (
+	"github.com/spf13/viper"
+	"github.com/webook/internal/repository"
+	"github.com/webook/internal/repository/dao"
+)
+
+func InitArticleRevisionRepository(d dao.ArticleRevisionDAO) repository.ArticleRevisionRepository {
+	type Config struct {
+		// 0 means retaining all revisions
+		MaxRevisions int `yaml:"maxRevisions"`
+	}
+	var cfg Config = Config{
+		MaxRevisions: 50,
+	}
+	err := viper.UnmarshalKey("article.revision", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return repository.NewArticleRevisionRepository(d, cfg.MaxRevisions)
+}
@@ -0,0 +1,34 @@
+package ioc
+
+import (
+	"github.com/spf13/viper"
+	"github.com/webook/internal/service/email"
+	"github.com/webook/internal/service/email/localemail"
+	"github.com/webook/internal/service/email/smtp"
+	"os"
+)
+
+// InitEmailService sends the emails through SMTP if it is configured, otherwise only logs them
+func InitEmailService() email.Service {
+	type Config struct {
+		Host     string `yaml:"host"`
+		Port     int    `yaml:"port"`
+		Username string `yaml:"username"`
+		From     string `yaml:"from"`
+	}
+	var cfg Config = Config{
+		Port: 587,
+	}
+	err := viper.UnmarshalKey("email.smtp", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.Host == "" {
+		return localemail.NewService()
+	}
+	password, ok := os.LookupEnv("SMTP_PASSWORD")
+	if !ok {
+		panic("cannot find environment variable SMTP_PASSWORD")
+	}
+	return smtp.NewService(cfg.Host, cfg.Port, cfg.Username, password, cfg.From)
+}
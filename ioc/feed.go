@@ -0,0 +1,24 @@
+package ioc
+
+import (
+	"github.com/spf13/viper"
+	"github.com/webook/internal/repository"
+	"github.com/webook/internal/service"
+)
+
+func InitFeedService(repo repository.FeedRepository,
+	followRepo repository.FollowRepository,
+	artRepo repository.ArticleRepository) service.FeedService {
+	type Config struct {
+		// authors with more followers than it are pulled by the followers instead of pushing
+		PushThreshold int64 `yaml:"pushThreshold"`
+	}
+	var cfg Config = Config{
+		PushThreshold: 1000,
+	}
+	err := viper.UnmarshalKey("feed", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return service.NewFeedService(repo, followRepo, artRepo, cfg.PushThreshold)
+}
@@ -0,0 +1,28 @@
+package ioc
+
+import (
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"github.com/webook/internal/repository/cache"
+	"time"
+)
+
+func InitReadDedupCache(client redis.Cmdable) cache.ReadDedupCache {
+	type Config struct {
+		// a reader is counted once in the unique read count within the window
+		Window time.Duration `yaml:"window"`
+	}
+	var cfg Config = Config{
+		Window: time.Hour * 24,
+	}
+	err := viper.UnmarshalKey("interactive.read", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	// the window is the divisor of the key
+	if cfg.Window < time.Millisecond {
+		panic(fmt.Sprintf("interactive.read.window must be at least 1ms, got %s", cfg.Window))
+	}
+	return cache.NewReadDedupRedisCache(client, cfg.Window)
+}
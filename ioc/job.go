@@ -0,0 +1,38 @@
+package ioc
+
+import (
+	"github.com/spf13/viper"
+	"github.com/webook/internal/job"
+	"github.com/webook/internal/service"
+	"github.com/webook/pkg/lock"
+	"github.com/webook/pkg/logger"
+	"time"
+)
+
+func InitScheduledPublishJob(svc service.ArticleService, locker lock.Locker, l logger.LoggerV1) *job.ScheduledPublishJob {
+	type Config struct {
+		Interval time.Duration `yaml:"interval"`
+	}
+	var cfg Config = Config{
+		Interval: time.Second * 10,
+	}
+	err := viper.UnmarshalKey("article.schedule", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return job.NewScheduledPublishJob(svc, locker, l, cfg.Interval)
+}
+
+func InitRankingJob(svc service.RankingService, locker lock.Locker, l logger.LoggerV1) *job.RankingJob {
+	type Config struct {
+		Interval time.Duration `yaml:"interval"`
+	}
+	var cfg Config = Config{
+		Interval: time.Minute * 3,
+	}
+	err := viper.UnmarshalKey("article.ranking", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return job.NewRankingJob(svc, locker, l, cfg.Interval)
+}
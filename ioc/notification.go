@@ -0,0 +1,25 @@
+package ioc
+
+import (
+	"github.com/webook/internal/domain"
+	"github.com/webook/internal/repository"
+	"github.com/webook/internal/service"
+	"github.com/webook/pkg/eventbus"
+)
+
+// InitNotificationService subscribes the notification center to the bus,
+// so that all the notifications published go into it
+func InitNotificationService(repo repository.NotificationRepository, bus eventbus.Bus) service.NotificationService {
+	svc := service.NewNotificationService(repo)
+	bus.Subscribe(domain.TopicNotification, svc.HandleEvent)
+	return svc
+}
+
+// InitUserEventService subscribes the user event streams to the bus,
+// the notifications are pushed to the streams as well
+func InitUserEventService(repo repository.UserEventRepository, bus eventbus.Bus) service.UserEventService {
+	svc := service.NewUserEventService(repo)
+	bus.Subscribe(domain.TopicUserEvent, svc.HandleEvent)
+	bus.Subscribe(domain.TopicNotification, svc.HandleNotification)
+	return svc
+}
@@ -5,7 +5,8 @@ import (
 	"github.com/spf13/viper"
 )
 
-func InitRedis() redis.Cmdable {
+// InitRedis returns the UniversalClient, the pub/sub needs more than redis.Cmdable
+func InitRedis() redis.UniversalClient {
 	// assume there is an independent Redis config file
 	return redis.NewClient(&redis.Options{
 		Addr: viper.GetString("redis.addr"),
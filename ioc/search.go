@@ -0,0 +1,23 @@
+package ioc
+
+import (
+	"github.com/webook/internal/repository"
+	"github.com/webook/internal/service"
+	"github.com/webook/pkg/search"
+)
+
+// InitSearchService keeps the indexes in process, each replica has its own copy
+// and follows the changes of the others through Redis
+func InitSearchService(artRepo repository.ArticleRepository, userRepo repository.UserRepository,
+	indexRepo repository.SearchIndexRepository) service.SearchService {
+	// a title match counts as much as three content matches
+	artIndex := search.NewMemoryIndex(map[string]float64{
+		"title":   3,
+		"content": 1,
+	})
+	userIndex := search.NewMemoryIndex(map[string]float64{
+		"nickname": 2,
+		"aboutMe":  1,
+	})
+	return service.NewSearchService(artIndex, userIndex, artRepo, userRepo, indexRepo)
+}
@@ -0,0 +1,24 @@
+package ioc
+
+import (
+	"github.com/spf13/viper"
+	"github.com/webook/internal/service"
+	"github.com/webook/internal/web"
+	"github.com/webook/pkg/logger"
+)
+
+func InitSyndicationHandler(l logger.LoggerV1, svc service.ArticleService, userSvc service.UserService) *web.SyndicationHandler {
+	type Config struct {
+		SiteURL string `yaml:"siteURL"`
+		Title   string `yaml:"title"`
+	}
+	var cfg Config = Config{
+		SiteURL: "http://localhost:3000",
+		Title:   "Webook",
+	}
+	err := viper.UnmarshalKey("syndication", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return web.NewSyndicationHandler(l, svc, userSvc, cfg.SiteURL, cfg.Title)
+}
@@ -0,0 +1,20 @@
+package ioc
+
+import (
+	"github.com/spf13/viper"
+	"github.com/webook/internal/service"
+)
+
+// InitUnverifiedUserPolicy reads what the users with unverified emails can do,
+// by default they can comment but not publish
+func InitUnverifiedUserPolicy() service.UnverifiedUserPolicy {
+	var cfg service.UnverifiedUserPolicy = service.UnverifiedUserPolicy{
+		Publish: false,
+		Comment: true,
+	}
+	err := viper.UnmarshalKey("user.unverified", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return cfg
+}
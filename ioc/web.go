@@ -27,13 +27,29 @@ import (
 
 func InitWebServer(mdls []gin.HandlerFunc,
 	userHdl *web.UserHandler,
-	//artHdl *web.ArticleHandler,
+	artHdl *web.ArticleHandler,
+	commentHdl *web.CommentHandler,
+	followHdl *web.FollowHandler,
+	feedHdl *web.FeedHandler,
+	notificationHdl *web.NotificationHandler,
+	eventHdl *web.EventHandler,
+	collectionHdl *web.CollectionHandler,
+	searchHdl *web.SearchHandler,
+	syndicationHdl *web.SyndicationHandler,
 	wechatHdl *web.OAuth2WechatHandler) *gin.Engine {
 	server := gin.Default()
 	server.Use(mdls...)
 	userHdl.RegisterRoutes(server)
 	wechatHdl.RegisterRoutes(server)
-	//artHdl.RegisterRoutes(server)
+	artHdl.RegisterRoutes(server)
+	commentHdl.RegisterRoutes(server)
+	followHdl.RegisterRoutes(server)
+	feedHdl.RegisterRoutes(server)
+	notificationHdl.RegisterRoutes(server)
+	eventHdl.RegisterRoutes(server)
+	collectionHdl.RegisterRoutes(server)
+	searchHdl.RegisterRoutes(server)
+	syndicationHdl.RegisterRoutes(server)
 	return server
 }
 
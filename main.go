@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
 	"github.com/gin-gonic/gin"
 	"net/http"
 )
 
 func main() {
-	server := InitWebServer()
+	app := InitApp()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	app.scheduledPublish.Start(ctx)
+	app.ranking.Start(ctx)
+	app.userEvents.Start(ctx)
+	app.search.Start(ctx)
+
+	server := app.server
 	server.GET("/hello", func(ctx *gin.Context) {
 		ctx.String(http.StatusOK, "hello，启动成功了！")
 	})
@@ -0,0 +1,39 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// MemoryBus delivers the events in process and synchronously,
+// so publishers see the errors and tests need no brokers
+type MemoryBus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewMemoryBus() Bus {
+	return &MemoryBus{
+		handlers: make(map[string][]Handler),
+	}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, topic string, payload any) error {
+	b.mu.RLock()
+	handlers := b.handlers[topic]
+	b.mu.RUnlock()
+	var errs []error
+	for _, h := range handlers {
+		if err := h(ctx, payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (b *MemoryBus) Subscribe(topic string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[topic] = append(b.handlers[topic], h)
+}
@@ -0,0 +1,14 @@
+package eventbus
+
+import "context"
+
+// Handler handles the payload of an event, the handler knows the payload type of its topic
+type Handler func(ctx context.Context, payload any) error
+
+// Bus decouples who produces the events from who consumes them
+type Bus interface {
+	// Publish delivers payload to all the handlers subscribed to topic,
+	// it returns the errors of the handlers joined, nil if there are no handlers
+	Publish(ctx context.Context, topic string, payload any) error
+	Subscribe(topic string, h Handler)
+}
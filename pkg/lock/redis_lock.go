@@ -0,0 +1,57 @@
+package lock
+
+import (
+	"context"
+	_ "embed"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+//go:embed unlock.lua
+var luaUnlock string
+
+type RedisLocker struct {
+	cmd redis.Cmdable
+}
+
+func NewRedisLocker(cmd redis.Cmdable) Locker {
+	return &RedisLocker{
+		cmd: cmd,
+	}
+}
+
+func (r *RedisLocker) TryLock(ctx context.Context, key string, expiration time.Duration) (Lock, error) {
+	// value identifies the holder, so that we never release others' lock
+	val := uuid.New().String()
+	ok, err := r.cmd.SetNX(ctx, key, val, expiration).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrLockNotHold
+	}
+	return &RedisLock{
+		cmd: r.cmd,
+		key: key,
+		val: val,
+	}, nil
+}
+
+type RedisLock struct {
+	cmd redis.Cmdable
+	key string
+	val string
+}
+
+func (l *RedisLock) Unlock(ctx context.Context) error {
+	res, err := l.cmd.Eval(ctx, luaUnlock, []string{l.key}, l.val).Int64()
+	if err != nil {
+		return err
+	}
+	if res == 0 {
+		// expired, or taken by someone else
+		return ErrLockNotHold
+	}
+	return nil
+}
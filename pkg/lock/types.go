@@ -0,0 +1,19 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrLockNotHold = errors.New("Lock is held by someone else")
+
+type Locker interface {
+	// TryLock does not wait, return ErrLockNotHold if the key is locked already
+	TryLock(ctx context.Context, key string, expiration time.Duration) (Lock, error)
+}
+
+type Lock interface {
+	// Unlock only releases the lock when it is still held by us
+	Unlock(ctx context.Context) error
+}
@@ -0,0 +1,7 @@
+-- only delete the lock when it is still held by us
+-- otherwise we may release the lock someone else acquired after ours expired
+if redis.call("get", KEYS[1]) == ARGV[1] then
+    return redis.call("del", KEYS[1])
+else
+    return 0
+end
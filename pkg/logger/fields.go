@@ -15,3 +15,7 @@ func Int64(key string, val int64) Field {
 func Int(key string, val int) Field {
 	return Field{Key: key, Val: val}
 }
+
+func Bool(key string, val bool) Field {
+	return Field{Key: key, Val: val}
+}
@@ -0,0 +1,38 @@
+package markup
+
+import (
+	"bytes"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"html"
+	"strings"
+)
+
+// md does not render raw HTML in markdown, and drops dangerous links like javascript:
+var md = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// MarkdownToHTML renders markdown into sanitized HTML
+func MarkdownToHTML(src string) string {
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(src), &buf); err != nil {
+		// goldmark only fails when writing to buf fails, fall back to plain text
+		return PlainToHTML(src)
+	}
+	return Sanitize(buf.String())
+}
+
+// PlainToHTML escapes plain text, blank lines separate paragraphs
+func PlainToHTML(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	var buf strings.Builder
+	for _, para := range strings.Split(src, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		buf.WriteString("<p>")
+		buf.WriteString(strings.ReplaceAll(html.EscapeString(para), "\n", "<br>"))
+		buf.WriteString("</p>\n")
+	}
+	return buf.String()
+}
@@ -0,0 +1,141 @@
+package markup
+
+import (
+	"bytes"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"net/url"
+	"strings"
+)
+
+// allowedAttrs is the whitelist of tags and their attributes,
+// tags not in it are removed but their children are kept
+var allowedAttrs = map[atom.Atom][]string{
+	atom.P: nil, atom.Br: nil, atom.Hr: nil, atom.Div: nil, atom.Span: nil,
+	atom.H1: nil, atom.H2: nil, atom.H3: nil, atom.H4: nil, atom.H5: nil, atom.H6: nil,
+	atom.Blockquote: nil, atom.Pre: nil, atom.Code: {"class"}, atom.Kbd: nil,
+	atom.Em: nil, atom.I: nil, atom.Strong: nil, atom.B: nil, atom.Del: nil, atom.S: nil,
+	atom.Sup: nil, atom.Sub: nil,
+	atom.Ul: nil, atom.Ol: {"start"}, atom.Li: nil,
+	atom.Table: nil, atom.Thead: nil, atom.Tbody: nil, atom.Tr: nil,
+	atom.Th: {"align"}, atom.Td: {"align"},
+	atom.A:   {"href", "title"},
+	atom.Img: {"src", "alt", "title"},
+	// task list of GFM
+	atom.Input: {"type", "checked", "disabled"},
+}
+
+// droppedTags are removed together with their children
+var droppedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Iframe: true, atom.Object: true,
+	atom.Embed: true, atom.Noscript: true, atom.Template: true, atom.Textarea: true,
+	atom.Select: true, atom.Button: true, atom.Form: true, atom.Svg: true, atom.Math: true,
+}
+
+var allowedSchemes = map[string]bool{
+	"http": true, "https": true, "mailto": true,
+}
+
+// Sanitize keeps the whitelisted tags and attributes only,
+// so that the HTML written by authors can be shown to readers safely
+func Sanitize(src string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(src), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return html.EscapeString(src)
+	}
+	var buf bytes.Buffer
+	for _, n := range nodes {
+		sanitizeNode(&buf, n)
+	}
+	return buf.String()
+}
+
+func sanitizeNode(buf *bytes.Buffer, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		buf.WriteString(html.EscapeString(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		// comments, doctype
+		return
+	}
+	if droppedTags[n.DataAtom] {
+		return
+	}
+	attrs, ok := allowedAttrs[n.DataAtom]
+	if !ok {
+		sanitizeChildren(buf, n)
+		return
+	}
+	if n.DataAtom == atom.Input && !isCheckbox(n) {
+		return
+	}
+	buf.WriteByte('<')
+	buf.WriteString(n.Data)
+	for _, attr := range n.Attr {
+		if !contains(attrs, attr.Key) {
+			continue
+		}
+		if (attr.Key == "href" || attr.Key == "src") && !isSafeURL(attr.Val) {
+			continue
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(attr.Key)
+		buf.WriteString(`="`)
+		buf.WriteString(html.EscapeString(attr.Val))
+		buf.WriteByte('"')
+	}
+	if n.DataAtom == atom.A {
+		buf.WriteString(` rel="nofollow noopener"`)
+	}
+	buf.WriteByte('>')
+	if isVoid(n.DataAtom) {
+		return
+	}
+	sanitizeChildren(buf, n)
+	buf.WriteString("</")
+	buf.WriteString(n.Data)
+	buf.WriteByte('>')
+}
+
+func sanitizeChildren(buf *bytes.Buffer, n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sanitizeNode(buf, c)
+	}
+}
+
+func isSafeURL(val string) bool {
+	u, err := url.Parse(strings.TrimSpace(val))
+	if err != nil {
+		return false
+	}
+	// relative url has no scheme
+	return u.Scheme == "" || allowedSchemes[strings.ToLower(u.Scheme)]
+}
+
+func isCheckbox(n *html.Node) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == "type" {
+			return attr.Val == "checkbox"
+		}
+	}
+	return false
+}
+
+func isVoid(a atom.Atom) bool {
+	return a == atom.Br || a == atom.Hr || a == atom.Img || a == atom.Input
+}
+
+func contains(attrs []string, key string) bool {
+	for _, attr := range attrs {
+		if attr == key {
+			return true
+		}
+	}
+	return false
+}
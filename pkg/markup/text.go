@@ -0,0 +1,70 @@
+package markup
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"strings"
+	"unicode"
+)
+
+// PlainText strips the markup of the HTML, code blocks and images are skipped
+func PlainText(src string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(src), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return ""
+	}
+	var buf strings.Builder
+	for _, n := range nodes {
+		collectText(&buf, n)
+	}
+	// collapse the whitespaces and line breaks
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+func collectText(buf *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		buf.WriteString(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	if n.DataAtom == atom.Pre || droppedTags[n.DataAtom] {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectText(buf, c)
+	}
+	// keep the words of different blocks apart
+	buf.WriteByte(' ')
+}
+
+// CountWords counts the words of text, every CJK character is a word,
+// so is every sequence of other letters and digits
+func CountWords(text string) (words int, cjk int) {
+	inWord := false
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			cjk++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' && inWord:
+			if !inWord {
+				words++
+				inWord = true
+			}
+		default:
+			inWord = false
+		}
+	}
+	return words, cjk
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+)
+
+// MemoryIndex is an inverted index in process, it is rebuilt on start.
+// Each field has a weight, the term frequency saturates so repeating a word doesn't win
+type MemoryIndex struct {
+	mu      sync.RWMutex
+	weights map[string]float64
+	// postings are the weighted term frequencies, token -> document id -> frequency
+	postings map[string]map[int64]float64
+	// tokens of each document, to remove its postings on update
+	tokens map[int64][]string
+}
+
+// NewMemoryIndex takes the field weights, the fields not in weights have weight 1
+func NewMemoryIndex(weights map[string]float64) Index {
+	return &MemoryIndex{
+		weights:  weights,
+		postings: make(map[string]map[int64]float64),
+		tokens:   make(map[int64][]string),
+	}
+}
+
+func (idx *MemoryIndex) Upsert(ctx context.Context, doc Document) error {
+	freqs := make(map[string]float64)
+	for field, text := range doc.Fields {
+		weight, ok := idx.weights[field]
+		if !ok {
+			weight = 1
+		}
+		tfs := make(map[string]int)
+		for _, token := range Tokenize(text, true) {
+			tfs[token]++
+		}
+		for token, tf := range tfs {
+			freqs[token] += weight * float64(tf) / float64(tf+1)
+		}
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.delete(doc.Id)
+	tokens := make([]string, 0, len(freqs))
+	for token, freq := range freqs {
+		docs, ok := idx.postings[token]
+		if !ok {
+			docs = make(map[int64]float64)
+			idx.postings[token] = docs
+		}
+		docs[doc.Id] = freq
+		tokens = append(tokens, token)
+	}
+	idx.tokens[doc.Id] = tokens
+	return nil
+}
+
+func (idx *MemoryIndex) Delete(ctx context.Context, id int64) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.delete(id)
+	return nil
+}
+
+func (idx *MemoryIndex) delete(id int64) {
+	for _, token := range idx.tokens[id] {
+		docs := idx.postings[token]
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(idx.postings, token)
+		}
+	}
+	delete(idx.tokens, id)
+}
+
+func (idx *MemoryIndex) Search(ctx context.Context, query string, offset int, limit int) ([]Hit, error) {
+	// the single characters are only searched when typed alone
+	tokens := Tokenize(query, false)
+	seen := make(map[string]struct{}, len(tokens))
+	scores := make(map[int64]float64)
+	idx.mu.RLock()
+	total := float64(len(idx.tokens))
+	for _, token := range tokens {
+		if _, ok := seen[token]; ok {
+			continue
+		}
+		seen[token] = struct{}{}
+		docs := idx.postings[token]
+		if len(docs) == 0 {
+			continue
+		}
+		// rare tokens count more
+		idf := math.Log(1 + total/float64(len(docs)))
+		for id, freq := range docs {
+			scores[id] += idf * freq
+		}
+	}
+	idx.mu.RUnlock()
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{Id: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		// the newer first
+		return hits[i].Id > hits[j].Id
+	})
+	if offset >= len(hits) {
+		return []Hit{}, nil
+	}
+	hits = hits[offset:]
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
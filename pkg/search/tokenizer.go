@@ -0,0 +1,57 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenize splits text into lowercase words. Chinese, Japanese and Korean have no spaces between words,
+// so their runs are split into overlapping bigrams, plus the single characters if unigrams is true
+func Tokenize(text string, unigrams bool) []string {
+	var tokens []string
+	var word strings.Builder
+	var cjk []rune
+	flushWord := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+	flushCJK := func() {
+		switch {
+		case len(cjk) == 0:
+		case len(cjk) == 1:
+			tokens = append(tokens, string(cjk))
+		default:
+			for i := 0; i+1 < len(cjk); i++ {
+				tokens = append(tokens, string(cjk[i:i+2]))
+			}
+			if unigrams {
+				for _, r := range cjk {
+					tokens = append(tokens, string(r))
+				}
+			}
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word.WriteRune(unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
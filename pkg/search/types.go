@@ -0,0 +1,25 @@
+package search
+
+import "context"
+
+// Document is what gets indexed, Fields are the texts to search keyed by the field name
+type Document struct {
+	Id     int64
+	Fields map[string]string
+}
+
+// Hit is a matched document, the higher Score the more relevant
+type Hit struct {
+	Id    int64
+	Score float64
+}
+
+// Index is a full-text index of one kind of documents
+type Index interface {
+	// Upsert replaces the document of the same id
+	Upsert(ctx context.Context, doc Document) error
+	// Delete is a no-op if the document isn't indexed
+	Delete(ctx context.Context, id int64) error
+	// Search returns the hits of query, the most relevant first
+	Search(ctx context.Context, query string, offset int, limit int) ([]Hit, error)
+}
@@ -3,8 +3,8 @@
 package main
 
 import (
-	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
 	"github.com/webook/internal/repository"
 	"github.com/webook/internal/repository/cache"
 	"github.com/webook/internal/repository/dao"
@@ -12,43 +12,100 @@ import (
 	"github.com/webook/internal/web"
 	ijwt "github.com/webook/internal/web/jwt"
 	"github.com/webook/ioc"
+	"github.com/webook/pkg/eventbus"
+	"github.com/webook/pkg/lock"
 )
 
-func InitWebServer() *gin.Engine {
+func InitApp() *App {
 	wire.Build(
 		// third party dependency
 		ioc.InitRedis,
+		wire.Bind(new(redis.Cmdable), new(redis.UniversalClient)),
 		ioc.InitDB,
 		ioc.InitLogger,
+		lock.NewRedisLocker,
+		eventbus.NewMemoryBus,
 
 		// DAO
 		dao.NewUserDAO,
-		//dao.NewArticleGORMDAO,
+		dao.NewArticleGORMDAO,
+		dao.NewArticleRevisionGORMDAO,
+		dao.NewInteractiveGORMDAO,
+		dao.NewCommentGORMDAO,
+		dao.NewFollowGORMDAO,
+		dao.NewFeedGORMDAO,
+		dao.NewNotificationGORMDAO,
+		dao.NewCollectionGORMDAO,
 
 		// cache
 		cache.NewCodeCache,
 		cache.NewUserCache,
-		//cache.NewArticleRedisCache,
+		cache.NewArticleRedisCache,
+		cache.NewTagCache,
+		cache.NewInteractiveRedisCache,
+		cache.NewRankingRedisCache,
+		cache.NewRankingLocalCache,
+		ioc.InitReadDedupCache,
+		cache.NewCommentRedisCache,
+		cache.NewFollowRedisCache,
+		cache.NewUserEventRedisCache,
+		cache.NewSearchIndexRedisCache,
 
 		// repository
 		repository.NewCachedUserRepository,
 		repository.NewCodeRepository,
-		//repository.NewCachedArticleRepositry,
+		repository.NewCachedArticleRepository,
+		ioc.InitArticleRevisionRepository,
+		repository.NewCachedInteractiveRepository,
+		repository.NewCachedRankingRepository,
+		repository.NewCachedCommentRepository,
+		repository.NewCachedFollowRepository,
+		repository.NewFeedRepository,
+		repository.NewNotificationRepository,
+		repository.NewUserEventRepository,
+		repository.NewSearchIndexRepository,
+		repository.NewCollectionRepository,
 
 		// service
 		ioc.InitSMSService,
+		ioc.InitEmailService,
 		ioc.InitWechatService,
+		ioc.InitUnverifiedUserPolicy,
 		service.NewUserService,
 		service.NewCodeService,
-		//service.NewArticleService,
+		service.NewEmailCodeService,
+		service.NewArticleService,
+		service.NewInteractiveService,
+		service.NewRankingService,
+		service.NewCommentService,
+		service.NewFollowService,
+		ioc.InitFeedService,
+		ioc.InitNotificationService,
+		ioc.InitUserEventService,
+		service.NewCollectionService,
+		ioc.InitSearchService,
 
 		// handler
 		web.NewUserHandler,
-		//web.NewArticleHandler,
+		web.NewArticleHandler,
+		web.NewCommentHandler,
+		web.NewFollowHandler,
+		web.NewFeedHandler,
+		web.NewNotificationHandler,
+		web.NewEventHandler,
+		web.NewCollectionHandler,
+		web.NewSearchHandler,
+		ioc.InitSyndicationHandler,
 		ijwt.NewRedisJWTHandler,
 		web.NewOAuth2WechatHandler,
 		ioc.InitGinMiddlewares,
 		ioc.InitWebServer,
+
+		// job
+		ioc.InitScheduledPublishJob,
+		ioc.InitRankingJob,
+
+		wire.Struct(new(App), "*"),
 	)
-	return gin.Default()
+	return new(App)
 }
@@ -7,7 +7,6 @@
 package main
 
 import (
-	"github.com/gin-gonic/gin"
 	"github.com/webook/internal/repository"
 	"github.com/webook/internal/repository/cache"
 	"github.com/webook/internal/repository/dao"
@@ -15,27 +14,91 @@ import (
 	"github.com/webook/internal/web"
 	"github.com/webook/internal/web/jwt"
 	"github.com/webook/ioc"
+	"github.com/webook/pkg/eventbus"
+	"github.com/webook/pkg/lock"
 )
 
 // Injectors from wire.go:
 
-func InitWebServer() *gin.Engine {
-	cmdable := ioc.InitRedis()
-	handler := jwt.NewRedisJWTHandler(cmdable)
+func InitApp() *App {
+	universalClient := ioc.InitRedis()
+	bus := eventbus.NewMemoryBus()
+	handler := jwt.NewRedisJWTHandler(universalClient, bus)
 	loggerV1 := ioc.InitLogger()
-	v := ioc.InitGinMiddlewares(cmdable, handler, loggerV1)
+	v := ioc.InitGinMiddlewares(universalClient, handler, loggerV1)
 	db := ioc.InitDB(loggerV1)
 	userDAO := dao.NewUserDAO(db)
-	userCache := cache.NewUserCache(cmdable)
+	userCache := cache.NewUserCache(universalClient)
 	userRepository := repository.NewCachedUserRepository(userDAO, userCache)
-	userService := service.NewUserService(userRepository)
-	codeCache := cache.NewCodeCache(cmdable)
+	articleDAO := dao.NewArticleGORMDAO(db)
+	articleCache := cache.NewArticleRedisCache(universalClient)
+	tagCache := cache.NewTagCache(universalClient)
+	articleRepository := repository.NewCachedArticleRepository(articleDAO, articleCache, tagCache)
+	searchIndexCache := cache.NewSearchIndexRedisCache(universalClient)
+	searchIndexRepository := repository.NewSearchIndexRepository(searchIndexCache)
+	searchService := ioc.InitSearchService(articleRepository, userRepository, searchIndexRepository)
+	unverifiedUserPolicy := ioc.InitUnverifiedUserPolicy()
+	userService := service.NewUserService(userRepository, bus, searchService, unverifiedUserPolicy)
+	codeCache := cache.NewCodeCache(universalClient)
 	codeRepository := repository.NewCodeRepository(codeCache)
 	smsService := ioc.InitSMSService()
 	codeService := service.NewCodeService(codeRepository, smsService)
-	userHandler := web.NewUserHandler(userService, codeService)
+	emailService := ioc.InitEmailService()
+	emailCodeService := service.NewEmailCodeService(codeRepository, emailService)
+	followDAO := dao.NewFollowGORMDAO(db)
+	followCache := cache.NewFollowRedisCache(universalClient)
+	followRepository := repository.NewCachedFollowRepository(followDAO, followCache)
+	feedDAO := dao.NewFeedGORMDAO(db)
+	feedRepository := repository.NewFeedRepository(feedDAO)
+	followService := service.NewFollowService(followRepository, feedRepository, userService)
+	userHandler := web.NewUserHandler(userService, handler, codeService, emailCodeService, followService)
+	articleRevisionDAO := dao.NewArticleRevisionGORMDAO(db)
+	articleRevisionRepository := ioc.InitArticleRevisionRepository(articleRevisionDAO)
+	feedService := ioc.InitFeedService(feedRepository, followRepository, articleRepository)
+	articleService := service.NewArticleService(articleRepository, articleRevisionRepository, userService, feedService, searchService)
+	interactiveDAO := dao.NewInteractiveGORMDAO(db)
+	interactiveCache := cache.NewInteractiveRedisCache(universalClient)
+	readDedupCache := ioc.InitReadDedupCache(universalClient)
+	interactiveRepository := repository.NewCachedInteractiveRepository(interactiveDAO, interactiveCache, readDedupCache)
+	collectionDAO := dao.NewCollectionGORMDAO(db)
+	collectionRepository := repository.NewCollectionRepository(collectionDAO)
+	interactiveService := service.NewInteractiveService(interactiveRepository, collectionRepository)
+	rankingCache := cache.NewRankingRedisCache(universalClient)
+	rankingLocalCache := cache.NewRankingLocalCache()
+	rankingRepository := repository.NewCachedRankingRepository(rankingCache, rankingLocalCache)
+	rankingService := service.NewRankingService(articleRepository, interactiveRepository, rankingRepository)
+	commentDAO := dao.NewCommentGORMDAO(db)
+	commentCache := cache.NewCommentRedisCache(universalClient)
+	commentRepository := repository.NewCachedCommentRepository(commentDAO, commentCache)
+	commentService := service.NewCommentService(commentRepository, articleRepository, userService)
+	articleHandler := web.NewArticleHandler(loggerV1, articleService, interactiveService, rankingService, commentService)
+	commentHandler := web.NewCommentHandler(loggerV1, commentService)
+	followHandler := web.NewFollowHandler(loggerV1, followService)
+	feedHandler := web.NewFeedHandler(loggerV1, feedService)
+	notificationDAO := dao.NewNotificationGORMDAO(db)
+	notificationRepository := repository.NewNotificationRepository(notificationDAO)
+	notificationService := ioc.InitNotificationService(notificationRepository, bus)
+	notificationHandler := web.NewNotificationHandler(loggerV1, notificationService)
+	userEventCache := cache.NewUserEventRedisCache(universalClient)
+	userEventRepository := repository.NewUserEventRepository(userEventCache)
+	userEventService := ioc.InitUserEventService(userEventRepository, bus)
+	eventHandler := web.NewEventHandler(loggerV1, userEventService, handler)
+	collectionService := service.NewCollectionService(collectionRepository, articleRepository)
+	collectionHandler := web.NewCollectionHandler(loggerV1, collectionService)
+	searchHandler := web.NewSearchHandler(loggerV1, searchService)
+	syndicationHandler := ioc.InitSyndicationHandler(loggerV1, articleService, userService)
 	wechatService := ioc.InitWechatService(loggerV1)
 	oAuth2WechatHandler := web.NewOAuth2WechatHandler(wechatService, handler, userService)
-	engine := ioc.InitWebServer(v, userHandler, oAuth2WechatHandler)
-	return engine
+	engine := ioc.InitWebServer(v, userHandler, articleHandler, commentHandler, followHandler, feedHandler, notificationHandler, eventHandler, collectionHandler, searchHandler, syndicationHandler, oAuth2WechatHandler)
+	locker := lock.NewRedisLocker(universalClient)
+	scheduledPublishJob := ioc.InitScheduledPublishJob(articleService, locker, loggerV1)
+	rankingJob := ioc.InitRankingJob(rankingService, locker, loggerV1)
+	app := &App{
+		server:           engine,
+		scheduledPublish: scheduledPublishJob,
+		ranking:          rankingJob,
+		userEvents:       userEventService,
+		search:           searchService,
+	}
+	return app
 }